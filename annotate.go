@@ -0,0 +1,60 @@
+package colorjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// IndexAnnotation selects how array elements are labeled with their index.
+type IndexAnnotation int
+
+const (
+	// IndexOff writes no labels.
+	IndexOff IndexAnnotation = iota
+	// IndexComment writes a comment before each element: /* 17 */.
+	IndexComment
+	// IndexGutter writes the index right-aligned in a column before each
+	// element, so the elements stay aligned: " 7│ ".
+	IndexGutter
+)
+
+const indexGutterMark = "│ "
+
+// indexLabel returns the annotation of the element at index in an array
+// whose last element is at last.
+func (f *Formatter) indexLabel(index, last int) string {
+	switch f.ArrayIndexes {
+	case IndexComment:
+		return f.sprintfColor(f.CommentColor, "/* %d */", index) + " "
+	case IndexGutter:
+		width := len(strconv.Itoa(last))
+		return f.sprintColor(f.CommentColor, fmt.Sprintf("%*d", width, index)+indexGutterMark)
+	}
+	return ""
+}
+
+// keyCountLabel returns the annotation of an object with n keys.
+func (f *Formatter) keyCountLabel(n int) string {
+	if !f.KeyCounts {
+		return ""
+	}
+	if n == 1 {
+		return " " + f.sprintColor(f.CommentColor, "/* 1 key */")
+	}
+	return " " + f.sprintfColor(f.CommentColor, "/* %d keys */", n)
+}
+
+// sizeLabel returns the annotation of an object or array with its size as
+// compact JSON, or "" for scalars and values encoding/json can't marshal.
+func (f *Formatter) sizeLabel(n node) string {
+	v := n.iface()
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil || len(data) == 0 || data[0] != '{' && data[0] != '[' {
+		return ""
+	}
+	return f.sprintfColor(f.CommentColor, "/* %s */", formatBytes(len(data))) + " "
+}
@@ -0,0 +1,273 @@
+package colorjson
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// sgrState is the text style set by SGR sequences.
+type sgrState struct {
+	fg, bg                                 string
+	bold, faint, italic, underline, strike bool
+}
+
+// apply updates s with the parameters of an SGR sequence.
+func (s *sgrState) apply(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*s = sgrState{}
+		case p == 1:
+			s.bold = true
+		case p == 2:
+			s.faint = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 9:
+			s.strike = true
+		case p == 22:
+			s.bold, s.faint = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p == 29:
+			s.strike = false
+		case p >= 30 && p <= 37, p >= 90 && p <= 97:
+			s.fg = "#" + color.Basic2hex(uint8(p))
+		case p >= 40 && p <= 47, p >= 100 && p <= 107:
+			s.bg = "#" + color.Basic2hex(uint8(p))
+		case p == 39:
+			s.fg = ""
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			hex, n := extendedColor(params[i+1:])
+			i += n
+			if p == 38 {
+				s.fg = hex
+			} else {
+				s.bg = hex
+			}
+		}
+	}
+}
+
+// extendedColor reads the "5;n" or "2;r;g;b" following a 38 or 48 and
+// returns the color and the number of parameters it used.
+func extendedColor(params []int) (string, int) {
+	switch {
+	case len(params) >= 2 && params[0] == 5:
+		rgb := color.C256ToRgb(uint8(params[1]))
+		return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2]), 2
+	case len(params) >= 4 && params[0] == 2:
+		return fmt.Sprintf("#%02x%02x%02x", uint8(params[1]), uint8(params[2]), uint8(params[3])), 4
+	}
+	return "", len(params)
+}
+
+// css returns the inline style for s, or "" for plain text.
+func (s sgrState) css() string {
+	var b strings.Builder
+	if s.fg != "" {
+		fmt.Fprintf(&b, "color: %s; ", s.fg)
+	}
+	if s.bg != "" {
+		fmt.Fprintf(&b, "background: %s; ", s.bg)
+	}
+	writeCSSAttrs(&b, s.bold, s.faint, s.italic, s.underline, s.strike)
+	return strings.TrimSpace(b.String())
+}
+
+// ansiConverter turns text with escape sequences into HTML.
+type ansiConverter struct {
+	w     *bufio.Writer
+	style sgrState
+	span  bool
+	link  string
+}
+
+// ANSIToHTML converts colored output, as this package writes it, read from
+// r into HTML spans with inline styles written to w. Hyperlinks to http,
+// https and mailto URLs become a elements, others plain text; other
+// sequences, such as inline images, are dropped.
+func ANSIToHTML(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	c := &ansiConverter{w: bufio.NewWriter(w)}
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if b != 0x1b {
+			c.writeText(b)
+			continue
+		}
+
+		kind, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case '[':
+			seq, final, err := readCSI(br)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if final == 'm' {
+				c.setStyle(seq)
+			}
+		case ']', '_', 'P', '^', 'X':
+			body, err := readString(br)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if kind == ']' && strings.HasPrefix(body, "8;") {
+				c.setLink(body)
+			}
+		}
+	}
+
+	c.closeSpan()
+	if c.link != "" {
+		c.w.WriteString("</a>")
+	}
+	return c.w.Flush()
+}
+
+// readCSI reads the parameters and final byte of a CSI sequence.
+func readCSI(br *bufio.Reader) (string, byte, error) {
+	var seq []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return "", 0, err
+		}
+		if b >= 0x40 && b <= 0x7e {
+			return string(seq), b, nil
+		}
+		seq = append(seq, b)
+	}
+}
+
+// readString reads the body of an OSC or other string sequence, up to BEL
+// or ST.
+func readString(br *bufio.Reader) (string, error) {
+	var body []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return string(body), err
+		}
+		switch {
+		case b == '\a':
+			return string(body), nil
+		case b == 0x1b:
+			if next, err := br.ReadByte(); err != nil || next == '\\' {
+				return string(body), err
+			}
+		default:
+			body = append(body, b)
+		}
+	}
+}
+
+func (c *ansiConverter) writeText(b byte) {
+	switch b {
+	case '&':
+		c.w.WriteString("&amp;")
+	case '<':
+		c.w.WriteString("&lt;")
+	case '>':
+		c.w.WriteString("&gt;")
+	default:
+		c.w.WriteByte(b)
+	}
+}
+
+func (c *ansiConverter) setStyle(seq string) {
+	var params []int
+	for _, field := range strings.Split(seq, ";") {
+		// An empty field, as in "\x1b[;1m", means 0.
+		p, _ := strconv.Atoi(field)
+		params = append(params, p)
+	}
+	if seq == "" {
+		params = nil
+	}
+
+	c.closeSpan()
+	c.style.apply(params)
+	c.openSpan()
+}
+
+// setLink starts or ends a hyperlink from an OSC 8 body, "8;params;uri".
+// Only http, https and mailto links are kept; the text of others, which
+// could run script in the page, is written without one.
+func (c *ansiConverter) setLink(body string) {
+	parts := strings.SplitN(body, ";", 3)
+	if len(parts) != 3 {
+		return
+	}
+
+	c.closeSpan()
+	if c.link != "" {
+		c.w.WriteString("</a>")
+	}
+	c.link = ""
+	if safeLink(parts[2]) {
+		c.link = parts[2]
+	}
+	if c.link != "" {
+		fmt.Fprintf(c.w, `<a href="%s">`, html.EscapeString(c.link))
+	}
+	c.openSpan()
+}
+
+// safeLink reports whether a link can be followed from a web page.
+func safeLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func (c *ansiConverter) openSpan() {
+	if css := c.style.css(); css != "" {
+		fmt.Fprintf(c.w, `<span style="%s">`, css)
+		c.span = true
+	}
+}
+
+func (c *ansiConverter) closeSpan() {
+	if c.span {
+		c.w.WriteString("</span>")
+		c.span = false
+	}
+}
@@ -0,0 +1,35 @@
+package colorjson
+
+import "bytes"
+
+// encodeAtomic renders root in full and hands it to the underlying writer
+// in a single Write, so output written to the same pipe from several
+// goroutines or processes never tears in the middle of a record or an
+// escape sequence. Pipes only guarantee that for writes of up to PIPE_BUF
+// bytes (4096 on Linux), however.
+func (f *Formatter) encodeAtomic(root node) error {
+	var buf bytes.Buffer
+	inner := f.clone(&buf)
+	inner.AtomicWrites = false
+	inner.AutoFlushEvery = 0
+	inner.lines = nil
+	err := inner.encode(root)
+	f.stats = inner.stats
+	if err != nil {
+		return err
+	}
+	return f.writeAtomic(buf.Bytes())
+}
+
+// writeAtomic writes p to the underlying writer in one Write. A bufio.Writer
+// passes a write on directly when it has nothing buffered and p doesn't
+// fit, and otherwise buffers p whole, so it is enough to flush around it.
+func (f *Formatter) writeAtomic(p []byte) error {
+	if err := f.Flush(); err != nil {
+		return err
+	}
+	if _, err := f.Buffer.Write(p); err != nil {
+		return err
+	}
+	return f.Flush()
+}
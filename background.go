@@ -0,0 +1,153 @@
+package colorjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Background is the brightness of the terminal background.
+type Background int
+
+const (
+	BackgroundUnknown Background = iota
+	BackgroundDark
+	BackgroundLight
+)
+
+// osc11Query asks the terminal for its background color.
+const osc11Query = "\x1b]11;?\x1b\\"
+
+var errNoReply = errors.New("colorjson: no reply to the background color query")
+
+// ProbeBackground asks the controlling terminal for its background color
+// with OSC 11, waiting at most timeout for the answer. It returns
+// BackgroundUnknown if there is no terminal or it doesn't answer.
+func ProbeBackground(timeout time.Duration) Background {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return BackgroundUnknown
+	}
+	defer tty.Close()
+
+	reply, err := queryTerminal(tty, osc11Query, timeout)
+	if err != nil {
+		return BackgroundUnknown
+	}
+	return parseOSC11(reply)
+}
+
+// queryTerminal writes query to tty in raw mode and returns the reply, up
+// to the string terminator.
+func queryTerminal(tty *os.File, query string, timeout time.Duration) ([]byte, error) {
+	// Without a deadline a terminal that doesn't answer would block the
+	// read, and swallow the user's next key press, forever.
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(int(tty.Fd()), state)
+
+	if _, err := tty.WriteString(query); err != nil {
+		return nil, err
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if bytes.HasSuffix(reply, []byte("\a")) || bytes.HasSuffix(reply, []byte("\x1b\\")) {
+			return reply, nil
+		}
+		if err != nil {
+			return nil, errNoReply
+		}
+	}
+}
+
+// parseOSC11 reads a reply such as "\x1b]11;rgb:ffff/ffff/ffff\x1b\\".
+func parseOSC11(reply []byte) Background {
+	i := bytes.Index(reply, []byte("rgb:"))
+	if i < 0 {
+		return BackgroundUnknown
+	}
+
+	var r, g, b uint64
+	if _, err := fmt.Sscanf(string(reply[i:]), "rgb:%x/%x/%x", &r, &g, &b); err != nil {
+		return BackgroundUnknown
+	}
+
+	// Components have one to four hex digits; the reply repeats the width of
+	// the first, so scale by it.
+	digits := bytes.IndexByte(reply[i+4:], '/')
+	max := float64(uint64(1)<<(4*digits) - 1)
+	return backgroundOf(float64(r)/max, float64(g)/max, float64(b)/max)
+}
+
+// backgroundOf classifies a color with components in [0, 1] by its
+// relative luminance.
+func backgroundOf(r, g, b float64) Background {
+	if 0.2126*r+0.7152*g+0.0722*b > 0.5 {
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
+
+// DetectBackground guesses the terminal background from the environment
+// without talking to the terminal. BackgroundEnv wins when set; otherwise
+// COLORFGBG, as set by rxvt, Konsole and others, and known terminal
+// defaults are consulted.
+func DetectBackground() Background {
+	switch strings.ToLower(os.Getenv(BackgroundEnv)) {
+	case "light":
+		return BackgroundLight
+	case "dark":
+		return BackgroundDark
+	}
+
+	if b := parseCOLORFGBG(os.Getenv("COLORFGBG")); b != BackgroundUnknown {
+		return b
+	}
+
+	// Terminal.app ships with a white background.
+	if os.Getenv("TERM_PROGRAM") == "Apple_Terminal" {
+		return BackgroundLight
+	}
+	return BackgroundUnknown
+}
+
+// parseCOLORFGBG reads a value such as "15;0" or "0;default;15", whose last
+// field is the ANSI palette index of the background.
+func parseCOLORFGBG(s string) Background {
+	i := strings.LastIndexByte(s, ';')
+	if i < 0 {
+		return BackgroundUnknown
+	}
+
+	bg, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return BackgroundUnknown
+	}
+	if bg == 7 || bg >= 9 && bg <= 15 {
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
+
+// DefaultTheme returns the default theme for a background: "light" on
+// light ones and "default" otherwise.
+func DefaultTheme(b Background) Theme {
+	t, _ := ThemeFor("default", b)
+	return t
+}
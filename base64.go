@@ -0,0 +1,76 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultBase64PreviewMaxBytes = 4 << 10
+
+// minBase64Preview is the length from which strings are tried as base64,
+// so short words that happen to decode aren't.
+const minBase64Preview = 8
+
+// decodeBase64 returns what s decodes to in standard or URL-safe base64,
+// with or without padding.
+func decodeBase64(s string) ([]byte, bool) {
+	if len(s) < minBase64Preview {
+		return nil, false
+	}
+	encodings := []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding}
+	if strings.HasSuffix(s, "=") {
+		encodings = []*base64.Encoding{base64.StdEncoding, base64.URLEncoding}
+	}
+	for _, enc := range encodings {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// printable reports whether data is text worth showing.
+func printable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeBase64Preview follows a string value with what it decodes to if it
+// is base64 encoded text or JSON.
+func (f *Formatter) writeBase64Preview(s string, w *bufio.Writer) (int, error) {
+	if !f.Base64Preview {
+		return 0, nil
+	}
+
+	max := f.Base64PreviewMaxBytes
+	if max == 0 {
+		max = defaultBase64PreviewMaxBytes
+	}
+	if len(s) > base64.StdEncoding.EncodedLen(max) {
+		return 0, nil
+	}
+	data, ok := decodeBase64(s)
+	if !ok || len(data) > max || !printable(data) {
+		return 0, nil
+	}
+
+	if v, ok := decodeSingle(string(data)); ok {
+		switch v.(type) {
+		case *object, []interface{}:
+			return w.WriteString(" " + f.sprintColor(f.CommentColor, "/* base64:") + " " + escapeComment(f.inlineJSON(v)) + " " + f.sprintColor(f.CommentColor, "*/"))
+		}
+	}
+	text, _ := json.Marshal(string(data))
+	return w.WriteString(" " + f.sprintfColor(f.CommentColor, "/* base64: %s */", escapeComment(string(text))))
+}
@@ -0,0 +1,167 @@
+package colorjson
+
+import (
+	"github.com/gookit/color"
+	"github.com/xo/terminfo"
+)
+
+// BasicThemes maps each of Themes onto the 16 basic ANSI colors. ThemeFor
+// and NewFormatter use them when the terminal supports no more, rather than
+// leaving 256 and 24-bit colors to be approximated.
+var BasicThemes = map[string]Theme{
+	"default": {
+		Back:   color.FgWhite,
+		Key:    color.FgLightWhite,
+		String: color.FgGreen,
+		Bool:   color.FgYellow,
+		Number: color.FgCyan,
+		Null:   color.FgMagenta,
+		Error:  color.FgRed,
+	},
+	"light": {
+		Back:   color.FgBlack,
+		Key:    color.FgDarkGray,
+		String: color.FgGreen,
+		Bool:   color.FgMagenta,
+		Number: color.FgBlue,
+		Null:   color.FgDarkGray,
+		Error:  color.FgRed,
+	},
+	"jq": {
+		Back:   color.FgWhite,
+		Key:    color.New(color.FgBlue, color.OpBold),
+		String: color.FgGreen,
+		Bool:   color.FgWhite,
+		Number: color.FgWhite,
+		Null:   color.FgDarkGray,
+		Error:  color.FgRed,
+	},
+	"jq-light": {
+		Back:   color.FgBlack,
+		Key:    color.New(color.FgBlue, color.OpBold),
+		String: color.FgGreen,
+		Bool:   color.FgBlack,
+		Number: color.FgBlack,
+		Null:   color.FgDarkGray,
+		Error:  color.FgRed,
+	},
+	"vivid": {
+		Back:   color.FgWhite,
+		Key:    Style{Color: color.FgBlue, Bold: true},
+		String: color.FgGreen,
+		Bool:   color.FgYellow,
+		Number: color.FgCyan,
+		Null:   Style{Color: color.FgMagenta, Italic: true},
+		Error:  Style{Color: color.FgWhite, Background: color.BgRed, Bold: true},
+	},
+	"vivid-light": {
+		Back:   color.FgBlack,
+		Key:    Style{Color: color.FgBlue, Bold: true},
+		String: color.FgGreen,
+		Bool:   color.FgRed,
+		Number: color.FgBlue,
+		Null:   Style{Color: color.FgMagenta, Italic: true},
+		Error:  Style{Color: color.FgWhite, Background: color.BgRed, Bold: true},
+	},
+	"monokai": {
+		Back:   color.FgLightWhite,
+		Key:    color.FgLightRed,
+		String: color.FgLightYellow,
+		Bool:   color.FgLightMagenta,
+		Number: color.FgLightMagenta,
+		Null:   color.FgLightCyan,
+		Error:  color.FgLightRed,
+	},
+	"monokai-light": {
+		Back:   color.FgBlack,
+		Key:    color.FgRed,
+		String: color.FgYellow,
+		Bool:   color.FgMagenta,
+		Number: color.FgMagenta,
+		Null:   color.FgCyan,
+		Error:  color.FgRed,
+	},
+	"solarized": {
+		Back:   color.FgWhite,
+		Key:    color.FgBlue,
+		String: color.FgCyan,
+		Bool:   color.FgYellow,
+		Number: color.FgMagenta,
+		Null:   color.FgLightBlue,
+		Error:  color.FgRed,
+	},
+	"solarized-light": {
+		Back:   color.FgDarkGray,
+		Key:    color.FgBlue,
+		String: color.FgCyan,
+		Bool:   color.FgYellow,
+		Number: color.FgMagenta,
+		Null:   color.FgLightBlue,
+		Error:  color.FgRed,
+	},
+	"minimal": {
+		Back:   Style{},
+		Key:    color.FgCyan,
+		String: Style{},
+		Bool:   Style{},
+		Number: Style{},
+		Null:   Style{},
+		Error:  Style{Bold: true},
+	},
+	"minimal-light": {
+		Back:   Style{},
+		Key:    color.FgBlue,
+		String: Style{},
+		Bool:   Style{},
+		Number: Style{},
+		Null:   Style{},
+		Error:  Style{Bold: true},
+	},
+	"grayscale": {
+		Back:   color.FgWhite,
+		Key:    Style{Color: color.FgLightWhite, Bold: true},
+		String: color.FgWhite,
+		Bool:   color.FgLightWhite,
+		Number: color.FgLightWhite,
+		Null:   Style{Color: color.FgDarkGray, Italic: true},
+		Error:  Style{Color: color.FgLightWhite, Underline: true},
+	},
+	"grayscale-light": {
+		Back:   color.FgDarkGray,
+		Key:    Style{Color: color.FgBlack, Bold: true},
+		String: color.FgBlack,
+		Bool:   color.FgBlack,
+		Number: color.FgBlack,
+		Null:   Style{Color: color.FgDarkGray, Italic: true},
+		Error:  Style{Color: color.FgBlack, Underline: true},
+	},
+	"monochrome": {
+		Back:   Style{Faint: true},
+		Key:    Style{Bold: true},
+		String: Style{},
+		Bool:   Style{Underline: true},
+		Number: Style{},
+		Null:   Style{Faint: true},
+		Error:  Style{Bold: true, Underline: true},
+	},
+}
+
+// termLevel is the color level of the terminal, detected before init
+// forces 24-bit colors, or set by COLORJSON_COLOR.
+var termLevel terminfo.ColorLevel
+
+// colorLevel returns the level colors are written at: the lower of
+// termLevel and the one set with color.ForceSetColorLevel. A terminal
+// whose level isn't known doesn't limit it.
+func colorLevel() terminfo.ColorLevel {
+	level := color.TermColorLevel()
+	if termLevel != terminfo.ColorLevelNone && termLevel < level {
+		level = termLevel
+	}
+	return level
+}
+
+// basicColors reports whether colors are limited to the 16 basic ones.
+func basicColors() bool {
+	return colorLevel() == terminfo.ColorLevelBasic
+}
@@ -0,0 +1,104 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Recorder is a writer that timestamps every chunk written through it, so
+// a colorized session can be saved as an asciinema cast.
+type Recorder struct {
+	// Width and Height are the terminal size stored in the cast.
+	Width  int
+	Height int
+	// Title, if set, is stored in the cast header.
+	Title string
+
+	w       io.Writer
+	mu      sync.Mutex
+	start   time.Time
+	events  []castEvent
+	pending []byte
+}
+
+type castEvent struct {
+	at   time.Duration
+	data string
+}
+
+// NewRecorder returns a recorder passing what it is given on to w, which
+// may be nil. The clock starts now.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		Width:  80,
+		Height: 24,
+		Title:  "",
+		w:      w,
+		start:  time.Now(),
+	}
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	// A rune split between chunks is held back until it is complete, as a
+	// cast event has to be valid UTF-8.
+	data := append(r.pending, p...)
+	n := completeRunes(data)
+	if n > 0 {
+		r.events = append(r.events, castEvent{at: time.Since(r.start), data: string(data[:n])})
+	}
+	r.pending = append([]byte(nil), data[n:]...)
+	r.mu.Unlock()
+
+	if r.w == nil {
+		return len(p), nil
+	}
+	return r.w.Write(p)
+}
+
+// completeRunes returns the length of data without a trailing incomplete
+// rune.
+func completeRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// WriteCast writes the recording to w in the asciinema v2 format. Line
+// feeds are written as a terminal shows them, "\r\n".
+func (r *Recorder) WriteCast(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	header := struct {
+		Version   int    `json:"version"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Timestamp int64  `json:"timestamp"`
+		Title     string `json:"title,omitempty"`
+	}{2, r.Width, r.Height, r.start.Unix(), r.Title}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, e := range r.events {
+		data := strings.ReplaceAll(strings.ReplaceAll(e.data, "\r\n", "\n"), "\n", "\r\n")
+		if err := enc.Encode([]interface{}{e.at.Seconds(), "o", data}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,193 @@
+// Command colorjson pretty prints JSON documents read from files or stdin.
+//
+// With the proxy subcommand it instead forwards HTTP requests to an
+// upstream server, printing the JSON bodies going either way:
+//
+//	colorjson proxy -listen :8080 http://localhost:3000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/olebeck/colorjson"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "proxy" {
+		proxy(os.Args[2:])
+		return
+	}
+
+	indent := flag.Int("indent", 2, "number of spaces to indent with")
+	noColor := flag.Bool("no-color", false, "disable colors")
+	query := flag.String("q", "", "only print what the query selects, e.g. '.items[] | select(.status==\"failed\")'")
+	levelKey := flag.String("level-key", "", "tint records by the log level in this field")
+	header := flag.String("header", "", "write a header line before each record, e.g. '{time} service={service}'")
+	separator := flag.String("separator", "", "write 'blank', 'rule', 'counter' or 'rs' (json-seq) between documents")
+	sse := flag.Bool("sse", false, "read Server-Sent Events, e.g. from curl -N, and colorize their data")
+	preset := flag.String("preset", "", "configure for a kind of document: "+presetNames())
+	mixed := flag.Bool("mixed", false, "read log text, colorizing the JSON embedded in its lines")
+	follow := flag.Bool("f", false, "follow the JSON lines file given, like tail -F")
+	jwt := flag.Bool("jwt", false, "show the decoded header and claims of JSON Web Tokens")
+	b64 := flag.Bool("base64", false, "show what base64 strings holding text or JSON decode to")
+	queryStrings := flag.Bool("query-strings", false, "show the parameters of query strings and form-encoded values")
+	csv := flag.Bool("csv", false, "write arrays of objects as CSV, with nested fields in columns such as user.name")
+	jsonrpc := flag.Bool("jsonrpc", false, "read JSON-RPC 2.0 messages, plain or framed by Content-Length as in LSP, pairing responses with requests")
+	tsv := flag.Bool("tsv", false, "like -csv with tabs, for pasting into spreadsheets; colored only on a terminal")
+	flag.Parse()
+
+	s := colorjson.NewStream(os.Stdout)
+	s.Formatter = colorjson.FromEnv(os.Stdout)
+	if _, ok := os.LookupEnv(colorjson.IndentEnv); !ok || isSet(flag.CommandLine, "indent") {
+		s.Indent = *indent
+	}
+	if *noColor {
+		s.DisabledColor = true
+	}
+	if *jwt {
+		s.JWTPreview = true
+	}
+	if *b64 {
+		s.Base64Preview = true
+	}
+	if *queryStrings {
+		s.QueryStringPreview = true
+	}
+	if *preset != "" {
+		p, ok := colorjson.Presets[*preset]
+		if !ok {
+			fatal(fmt.Errorf("unknown preset %q, want one of %s", *preset, presetNames()))
+		}
+		p(s)
+	}
+	if isSet(flag.CommandLine, "level-key") {
+		s.LevelKey = *levelKey
+	}
+	s.Header = *header
+	sep, ok := separators[*separator]
+	if !ok {
+		fatal(fmt.Errorf("unknown separator %q", *separator))
+	}
+	s.Separator = sep
+
+	if *query != "" {
+		q, err := colorjson.ParseQuery(*query)
+		if err != nil {
+			fatal(err)
+		}
+		s.Query = q
+	}
+
+	mode := ""
+	for _, m := range []struct {
+		name string
+		set  bool
+	}{{"sse", *sse}, {"mixed", *mixed}, {"csv", *csv}, {"tsv", *tsv}, {"jsonrpc", *jsonrpc}} {
+		if !m.set {
+			continue
+		}
+		if mode != "" {
+			fatal(fmt.Errorf("-%s and -%s can't be combined", mode, m.name))
+		}
+		mode = m.name
+	}
+
+	if *follow {
+		if flag.NArg() != 1 {
+			fatal(fmt.Errorf("-f needs exactly one file"))
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := s.Follow(ctx, flag.Arg(0)); err != nil && err != context.Canceled {
+			fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 {
+		if err := copyInput(s, os.Stdin, mode); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	for _, name := range flag.Args() {
+		if err := copyFile(s, name, mode); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+var separators = map[string]colorjson.Separator{
+	"":        colorjson.SeparatorNone,
+	"blank":   colorjson.SeparatorBlank,
+	"rule":    colorjson.SeparatorRule,
+	"counter": colorjson.SeparatorCounter,
+	"rs":      colorjson.SeparatorRS,
+}
+
+func presetNames() string {
+	var names []string
+	for name := range colorjson.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func copyFile(s *colorjson.Stream, name string, mode string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return copyInput(s, file, mode)
+}
+
+func copyInput(s *colorjson.Stream, r io.Reader, mode string) error {
+	switch mode {
+	case "sse":
+		return s.CopySSE(r)
+	case "mixed":
+		return s.CopyMixed(r)
+	case "jsonrpc":
+		return s.CopyJSONRPC(r, colorjson.RPCInferred)
+	case "csv":
+		return s.CopyCSV(r, colorjson.CSVFlatten("."))
+	case "tsv":
+		opts := []colorjson.CSVOption{colorjson.CSVComma('\t'), colorjson.CSVFlatten("."), colorjson.CSVEscapeFormulas()}
+		if isTerminal(os.Stdout) {
+			opts = append(opts, colorjson.CSVColors(s.Formatter))
+		}
+		return s.CopyCSV(r, opts...)
+	}
+	return s.Copy(r)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func isSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "colorjson:", err)
+	os.Exit(1)
+}
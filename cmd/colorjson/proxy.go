@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/olebeck/colorjson"
+	"github.com/olebeck/colorjson/httpdump"
+)
+
+// proxy runs the proxy subcommand.
+func proxy(args []string) {
+	flags := flag.NewFlagSet("proxy", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:8080", "address to listen on")
+	indent := flags.Int("indent", 2, "number of spaces to indent with")
+	noColor := flags.Bool("no-color", false, "disable colors")
+	maxBody := flags.Int("max-body", httpdump.DefaultMaxBody, "largest body in bytes that is printed")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: colorjson proxy [flags] upstream-url")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	upstream, err := url.Parse(flags.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	if upstream.Scheme == "" || upstream.Host == "" {
+		fatal(fmt.Errorf("upstream %q is not an absolute URL", flags.Arg(0)))
+	}
+
+	l := httpdump.New(os.Stdout)
+	l.Formatter = colorjson.FromEnv(os.Stdout)
+	if _, ok := os.LookupEnv(colorjson.IndentEnv); !ok || isSet(flags, "indent") {
+		l.Formatter.Indent = *indent
+	}
+	if *noColor {
+		l.Formatter.DisabledColor = true
+	}
+	l.MaxBody = *maxBody
+
+	fmt.Fprintf(os.Stderr, "colorjson: proxying %s to %s\n", *listen, upstream)
+	fatal(http.ListenAndServe(*listen, l.ReverseProxy(upstream)))
+}
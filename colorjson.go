@@ -2,10 +2,12 @@ package colorjson
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -14,47 +16,191 @@ import (
 )
 
 const initialDepth = 0
-const valueSep = ","
+const defaultElementSep = ", "
+const defaultKeyValueSep = ": "
 const null = "null"
 const startMap = "{"
 const endMap = "}"
 const startArray = "["
 const endArray = "]"
 
+const unexportedMark = "~"
+const filteredMark = "…filtered"
+const foldOpen = "{{{"
+const foldClose = "}}}"
+
 const emptyMap = startMap + endMap
 const emptyArray = startArray + endArray
 
+var numberType = reflect.TypeOf(json.Number(""))
+
 type Formatter struct {
-	Buffer          *bufio.Writer
-	BackColor       color.PrinterFace
-	KeyColor        color.PrinterFace
-	StringColor     color.PrinterFace
-	BoolColor       color.PrinterFace
-	NumberColor     color.PrinterFace
-	NullColor       color.PrinterFace
-	StringMaxLength int
-	Indent          int
-	DisabledColor   bool
-	RawStrings      bool
+	Buffer                *bufio.Writer
+	BackColor             color.PrinterFace
+	KeyColor              color.PrinterFace
+	StringColor           color.PrinterFace
+	BoolColor             color.PrinterFace
+	NumberColor           color.PrinterFace
+	NullColor             color.PrinterFace
+	ErrorColor            color.PrinterFace
+	UnexportedColor       color.PrinterFace
+	FilteredColor         color.PrinterFace
+	CommentColor          color.PrinterFace
+	HighlightColor        color.PrinterFace
+	FrameColor            color.PrinterFace
+	FrameTitleColor       color.PrinterFace
+	StringMaxLength       int
+	Indent                int
+	InlineShort           int
+	ScalarArraysInline    bool
+	GeoJSON               bool
+	MaxWidth              int
+	TerminateWithNewline  bool
+	Frame                 bool
+	FrameTitle            string
+	NonStrict             bool
+	TrailingCommas        bool
+	CommaFirst            bool
+	DisabledColor         bool
+	FaintPunctuation      bool
+	ColoredElements       Elements
+	RawStrings            bool
+	UseStringer           bool
+	StdlibCompat          bool
+	Verify                bool
+	TintQuoted            bool
+	ExpandErrors          bool
+	IncludeUnexported     bool
+	FieldOrder            FieldOrder
+	SortKeys              bool
+	KeyOrder              []string
+	KeyTransform          func(string) string
+	UnquotedKeys          bool
+	KeyValueSeparator     string
+	ElementSeparator      string
+	Decorations           *Decorations
+	Icons                 *IconSet
+	EmojiMarkers          bool
+	IncludeKeys           []string
+	ExcludeKeys           []string
+	FilterRules           []FilterRule
+	FilterFunc            func(path string, v interface{}) bool
+	ShowFiltered          bool
+	FoldMarkers           bool
+	ArrayIndexes          IndexAnnotation
+	KeyCounts             bool
+	SizeDepth             int
+	DepthGutter           GutterStyle
+	ImagePreview          ImagePreview
+	ImagePreviewMaxBytes  int
+	JWTPreview            bool
+	Base64Preview         bool
+	Base64PreviewMaxBytes int
+	QueryStringPreview    bool
+	FileLinks             bool
+	HighlightKeys         []string
+	KeyStyles             map[string]color.PrinterFace
+	ValueColor            func(path, value string) color.PrinterFace
+	AutoFlushEvery        int
+	AtomicWrites          bool
+	OnProgress            func(bytesWritten, nodesVisited int)
+
+	included    int
+	path        []string
+	pendingFold string
+	lines       *lineWriter
+	stats       Stats
+	counter     *countWriter
+	// linkOf, set by presets, returns the target a string value links to,
+	// or "".
+	linkOf func(path, value string) string
+	// geometry is set while the coordinates of a GeoJSON geometry are
+	// written.
+	geometry bool
 }
 
 func init() {
+	termLevel = color.DetectColorLevel()
 	color.ForceSetColorLevel(terminfo.ColorLevelMillions)
 }
 
 func NewFormatter(w io.Writer) *Formatter {
 	f := &Formatter{
-		Buffer:          bufio.NewWriter(w),
-		BackColor:       color.FgWhite,
-		KeyColor:        color.C256(250),
-		StringColor:     color.FgGreen,
-		BoolColor:       color.FgYellow,
-		NumberColor:     color.FgCyan,
-		NullColor:       color.FgMagenta,
-		StringMaxLength: 0,
-		DisabledColor:   false,
-		Indent:          0,
-		RawStrings:      false,
+		Buffer:                bufio.NewWriter(w),
+		BackColor:             color.FgWhite,
+		KeyColor:              color.C256(250),
+		StringColor:           color.FgGreen,
+		BoolColor:             color.FgYellow,
+		NumberColor:           color.FgCyan,
+		NullColor:             color.FgMagenta,
+		ErrorColor:            color.FgRed,
+		UnexportedColor:       color.C256(244),
+		FilteredColor:         color.New(color.OpFuzzy),
+		CommentColor:          color.New(color.OpFuzzy),
+		HighlightColor:        color.New(color.FgBlack, color.BgYellow),
+		FrameColor:            color.New(color.OpFuzzy),
+		FrameTitleColor:       color.New(color.FgCyan, color.OpBold),
+		StringMaxLength:       0,
+		DisabledColor:         false,
+		FaintPunctuation:      false,
+		ColoredElements:       ElementAll,
+		Indent:                0,
+		InlineShort:           0,
+		ScalarArraysInline:    false,
+		GeoJSON:               false,
+		MaxWidth:              0,
+		TerminateWithNewline:  false,
+		Frame:                 false,
+		FrameTitle:            "",
+		NonStrict:             false,
+		TrailingCommas:        false,
+		CommaFirst:            false,
+		RawStrings:            false,
+		UseStringer:           false,
+		StdlibCompat:          false,
+		Verify:                false,
+		TintQuoted:            false,
+		ExpandErrors:          false,
+		IncludeUnexported:     false,
+		FieldOrder:            DeclarationOrder,
+		SortKeys:              false,
+		KeyOrder:              nil,
+		KeyTransform:          nil,
+		UnquotedKeys:          false,
+		KeyValueSeparator:     "",
+		ElementSeparator:      "",
+		Decorations:           nil,
+		Icons:                 nil,
+		EmojiMarkers:          false,
+		IncludeKeys:           nil,
+		ExcludeKeys:           nil,
+		FilterRules:           nil,
+		FilterFunc:            nil,
+		ShowFiltered:          false,
+		FoldMarkers:           false,
+		ArrayIndexes:          IndexOff,
+		KeyCounts:             false,
+		SizeDepth:             0,
+		DepthGutter:           GutterOff,
+		ImagePreview:          ImagePreviewOff,
+		ImagePreviewMaxBytes:  defaultImagePreviewMaxBytes,
+		JWTPreview:            false,
+		Base64Preview:         false,
+		Base64PreviewMaxBytes: defaultBase64PreviewMaxBytes,
+		QueryStringPreview:    false,
+		FileLinks:             false,
+		KeyStyles:             nil,
+		ValueColor:            nil,
+		AutoFlushEvery:        0,
+		AtomicWrites:          false,
+		OnProgress:            nil,
+	}
+	if basicColors() {
+		f.SetTheme(BasicThemes["default"])
+		f.UnexportedColor = color.FgDarkGray
+	}
+	if paletteAdjusted() {
+		f.adjustPalette(DetectPalette())
 	}
 	return f
 }
@@ -78,122 +224,384 @@ func (f *Formatter) writeIndent(w *bufio.Writer, depth int) (int, error) {
 }
 
 func (f *Formatter) writeObjSep(w *bufio.Writer) (int, error) {
+	n, err := f.writeSep(w)
+	if err != nil {
+		return n, err
+	}
+
+	if f.AutoFlushEvery > 0 && w.Buffered() >= f.AutoFlushEvery {
+		err = w.Flush()
+	}
+	return n, err
+}
+
+func (f *Formatter) writeSep(w *bufio.Writer) (int, error) {
 	if f.Indent != 0 {
-		return w.WriteRune('\n')
+		n, err := f.writeFoldMarker(w)
+		if err != nil {
+			return n, err
+		}
+		m, err := w.WriteRune('\n')
+		return n + m, err
 	} else {
-		return w.WriteRune(' ')
+		return w.WriteString(f.decorations().Padding)
 	}
 }
 
-func (f *Formatter) Encode(jsonObj interface{}) error {
-	if s, ok := jsonObj.(string); ok {
-		f.Buffer.WriteString(s)
-		return f.Buffer.Flush()
+// writeItemStart indents the i-th member or element of a container. In
+// the comma-first style the separator goes here, in front of the item.
+func (f *Formatter) writeItemStart(w *bufio.Writer, depth, i int) (int, error) {
+	if i == 0 || !f.commaFirst() {
+		return f.writeIndent(w, depth+1)
 	}
-	_, err := f.marshalValue(reflect.ValueOf(jsonObj), f.Buffer, initialDepth)
-	if err != nil {
-		return err
+
+	sep := f.lineSep()
+	pad := f.Indent - len(sep)
+	if pad < 1 {
+		pad = 1
+	}
+	return w.WriteString(strings.Repeat(" ", f.Indent*depth) + f.punct(sep) + strings.Repeat(" ", pad))
+}
+
+// writeItemEnd follows the i-th of count members or elements with its
+// separator and the line break.
+func (f *Formatter) writeItemEnd(w *bufio.Writer, i, count int) (int, error) {
+	last := i == count-1
+	if f.commaFirst() || last && !(f.NonStrict && f.TrailingCommas) {
+		return f.writeObjSep(w)
+	}
+	if f.Indent == 0 && !last {
+		return w.WriteString(f.punct(f.elementSep()))
 	}
 
-	err = f.Buffer.Flush()
+	wr, err := w.WriteString(f.punct(f.lineSep()))
 	if err != nil {
-		return err
+		return wr, err
 	}
 
-	return nil
+	n, err := f.writeObjSep(w)
+	return wr + n, err
 }
 
-func (f *Formatter) marshalStruct(m reflect.Value, w *bufio.Writer, depth int) (int, error) {
-	remaining := m.NumField()
-	t := m.Type()
-
-	if remaining == 0 {
-		return w.WriteString(f.sprintColor(f.BackColor, emptyMap))
+// keyValueSep returns KeyValueSeparator, or that of the decorations if it
+// is empty.
+func (f *Formatter) keyValueSep() string {
+	if f.KeyValueSeparator == "" {
+		return f.decorations().KeyValueSeparator
 	}
+	return f.KeyValueSeparator
+}
 
-	var wr int
-	n, err := w.WriteString(f.sprintColor(f.BackColor, startMap))
-	if err != nil {
-		return wr, err
+// elementSep returns ElementSeparator, or that of the decorations if it is
+// empty.
+func (f *Formatter) elementSep() string {
+	if f.ElementSeparator == "" {
+		return f.decorations().ElementSeparator
 	}
+	return f.ElementSeparator
+}
 
-	wr += n
+func escapePercent(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
 
-	n, err = f.writeObjSep(w)
-	if err != nil {
-		return n, err
+// lineSep is the element separator used where a line break follows or
+// precedes it, without the spacing meant for single line output.
+func (f *Formatter) lineSep() string {
+	return strings.TrimSpace(f.elementSep())
+}
+
+// commaFirst reports whether separators start lines instead of ending them.
+func (f *Formatter) commaFirst() bool {
+	return f.NonStrict && f.CommaFirst && f.Indent != 0
+}
+
+// openFold and closeFold queue an editor fold marker for the end of the
+// current line when FoldMarkers is set.
+func (f *Formatter) openFold() {
+	if f.FoldMarkers && f.Indent != 0 {
+		f.pendingFold = foldOpen
 	}
+}
 
-	wr += n
+func (f *Formatter) closeFold() {
+	if f.FoldMarkers && f.Indent != 0 {
+		f.pendingFold = foldClose
+	}
+}
 
-	for i := 0; i < m.NumField(); i++ {
-		n, err = f.writeIndent(w, depth+1)
-		if err != nil {
-			return wr, err
+func (f *Formatter) writeFoldMarker(w *bufio.Writer) (int, error) {
+	if f.pendingFold == "" {
+		return 0, nil
+	}
+	marker := f.pendingFold
+	f.pendingFold = ""
+	return w.WriteString(" " + f.sprintColor(f.CommentColor, "// "+marker))
+}
+
+// Encode writes jsonObj. Options override the formatter's settings for this
+// call only.
+func (f *Formatter) Encode(jsonObj interface{}, opts ...Option) error {
+	f = f.withOptions(opts)
+	if s, ok := jsonObj.(string); ok {
+		if f.TerminateWithNewline {
+			s += "\n"
 		}
+		if f.AtomicWrites {
+			f.stats = Stats{Bytes: len(s)}
+			return f.writeAtomic([]byte(s))
+		}
+		n, _ := f.Buffer.WriteString(s)
+		f.stats = Stats{Bytes: n}
+		return f.Flush()
+	}
+	return f.EncodeValue(reflect.ValueOf(jsonObj))
+}
 
-		wr += n
+// EncodeValue writes v without going through Interface(), so values obtained
+// from unexported fields can be encoded as well. The zero Value is written as null.
+func (f *Formatter) EncodeValue(v reflect.Value, opts ...Option) error {
+	f = f.withOptions(opts)
+	return f.encode(node{value: v})
+}
+
+// encode writes a top-level value and flushes the buffer.
+func (f *Formatter) encode(root node) error {
+	if f.AtomicWrites {
+		return f.encodeAtomic(root)
+	}
+	if f.Frame {
+		return f.encodeFramed(root)
+	}
+	if f.DepthGutter != GutterOff {
+		return f.encodeGutter(root)
+	}
+	if f.Verify {
+		return f.encodeVerified(root)
+	}
 
-		keyName := t.Field(i).Name
+	f.path = f.path[:0]
+	f.pendingFold = ""
+	f.stats = Stats{}
+	defer f.startProgress()()
 
-		n, err = w.WriteString(f.KeyColor.Sprintf("\"%s\": ", keyName))
+	if f.StdlibCompat {
+		var err error
+		root, err = compatNode(root)
 		if err != nil {
-			return wr, err
+			return err
 		}
+	}
 
-		wr += n
+	n, err := f.marshalNode(root, f.Buffer, initialDepth)
+	f.stats.Bytes += n
+	if err != nil {
+		return err
+	}
 
-		n, err = f.marshalValue(m.Field(i), w, depth+1)
+	n, err = f.writeFoldMarker(f.Buffer)
+	f.stats.Bytes += n
+	if err != nil {
+		return err
+	}
+
+	if f.TerminateWithNewline {
+		n, err = f.Buffer.WriteString("\n")
+		f.stats.Bytes += n
 		if err != nil {
-			return wr, err
+			return err
 		}
+	}
 
-		wr += n
+	err = f.Flush()
+	if err != nil {
+		return err
+	}
 
-		remaining--
-		if remaining != 0 {
-			n, err = w.WriteString(f.sprintColor(f.BackColor, valueSep))
-			if err != nil {
-				return wr, err
-			}
+	if f.OnProgress != nil {
+		f.reportProgress()
+	}
 
-			wr += n
-		}
+	return nil
+}
+
+// Flush writes out buffered output, including a pending last line in line
+// callback mode. Encode flushes when it is done; AutoFlushEvery flushes
+// while it runs.
+func (f *Formatter) Flush() error {
+	err := f.Buffer.Flush()
+	if err != nil {
+		return err
+	}
+
+	if f.lines != nil {
+		f.lines.flush()
+	}
+
+	return nil
+}
+
+// node is a value waiting to be written. It either wraps a reflect.Value or,
+// on the EncodeT fast path, holds the plain Go value. quoted is set for
+// struct fields with the ,string tag option.
+type node struct {
+	value    reflect.Value
+	plain    interface{}
+	fast     bool
+	filtered bool
+	quoted   bool
+}
 
-		n, err = f.writeObjSep(w)
+// member is a single key/value pair of an object.
+type member struct {
+	key string
+	// rawKey, if set, is the key as encoding/json wrote it.
+	rawKey     rawString
+	unexported bool
+	included   bool
+	geometry   bool
+	node
+}
+
+// iface returns the value of n for user callbacks, or nil if it can't be
+// obtained without panicking.
+func (n node) iface() interface{} {
+	if n.fast {
+		return n.plain
+	}
+	if n.value.IsValid() && n.value.CanInterface() {
+		return n.value.Interface()
+	}
+	return nil
+}
+
+// reflect returns n as a reflect.Value regardless of how it is held.
+func (n node) reflect() reflect.Value {
+	if n.fast {
+		return reflect.ValueOf(n.plain)
+	}
+	return n.value
+}
+
+func (f *Formatter) marshalNode(n node, w *bufio.Writer, depth int) (int, error) {
+	f.visit()
+	if depth > f.stats.MaxDepth {
+		f.stats.MaxDepth = depth
+	}
+	if n.filtered {
+		return w.WriteString(f.sprintColor(f.FilteredColor, filteredMark))
+	}
+	if f.geometry {
+		if rank := coordinateRank(n.reflect()); rank == 1 || rank == 2 {
+			return f.marshalPositions(n, w, depth, rank)
+		}
+	}
+	if f.SizeDepth > 0 && depth > initialDepth && depth <= initialDepth+f.SizeDepth {
+		wr, err := w.WriteString(f.sizeLabel(n))
 		if err != nil {
 			return wr, err
 		}
+		n, err := f.marshalNodeValue(n, w, depth)
+		return wr + n, err
+	}
+	return f.marshalNodeValue(n, w, depth)
+}
 
-		wr += n
+func (f *Formatter) marshalNodeValue(n node, w *bufio.Writer, depth int) (int, error) {
+	if n.fast {
+		return f.marshalAny(n.plain, w, depth)
+	}
+	if n.quoted {
+		return f.marshalQuoted(n.value, w, depth)
 	}
+	return f.marshalValue(n.value, w, depth)
+}
 
-	n, err = f.writeIndent(w, depth)
-	if err != nil {
-		return wr, err
+func (f *Formatter) marshalMember(m member, w *bufio.Writer, depth int) (int, error) {
+	f.pushKey(m.key)
+	defer f.pop()
+
+	if m.geometry {
+		f.geometry = true
+		defer func() { f.geometry = false }()
 	}
 
-	wr += n
+	if m.included {
+		f.included++
+		defer func() { f.included-- }()
+	}
+	return f.marshalNode(m.node, w, depth)
+}
 
-	n, err = w.WriteString(f.sprintColor(f.BackColor, endMap))
-	if err != nil {
-		return wr, err
+func (f *Formatter) marshalStruct(m reflect.Value, w *bufio.Writer, depth int) (int, error) {
+	fields := orderFields(cachedFields(m.Type()), f.FieldOrder, f.fieldKey)
+
+	members := make([]member, 0, len(fields))
+	for _, fd := range fields {
+		if fd.unexported && !f.IncludeUnexported {
+			continue
+		}
+		value, ok := fieldByIndex(m, fd.index)
+		if !ok || fd.omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		members = append(members, member{key: f.fieldKey(fd), unexported: fd.unexported, node: node{value: value, quoted: fd.quoted}})
 	}
 
-	wr += n
+	return f.marshalObject(members, w, depth)
+}
 
-	return wr, nil
+// fieldKey returns the output key of fd, applying KeyTransform to names
+// that didn't come from a json tag.
+func (f *Formatter) fieldKey(fd field) string {
+	if !fd.tagged && f.KeyTransform != nil {
+		return f.KeyTransform(fd.name)
+	}
+	return fd.name
 }
 
 func (f *Formatter) marshalMap(m reflect.Value, w *bufio.Writer, depth int) (int, error) {
-	remaining := m.Len()
+	members := make([]member, 0, m.Len())
+	for _, key := range m.MapKeys() {
+		name, err := mapKey(key)
+		if err != nil {
+			return 0, err
+		}
+		members = append(members, member{key: name, node: node{value: m.MapIndex(key)}})
+	}
 
-	if remaining == 0 {
-		return w.WriteString(f.sprintColor(f.BackColor, emptyMap))
+	return f.marshalObject(members, w, depth)
+}
+
+func (f *Formatter) marshalObject(members []member, w *bufio.Writer, depth int) (int, error) {
+	members = f.filterMembers(members, depth+1)
+	f.markGeometry(members)
+	if f.SortKeys {
+		sort.SliceStable(members, func(i, j int) bool { return members[i].key < members[j].key })
+	}
+	if len(f.KeyOrder) > 0 {
+		sort.SliceStable(members, func(i, j int) bool { return keyRank(f.KeyOrder, members[i].key) < keyRank(f.KeyOrder, members[j].key) })
 	}
+	wr, err := f.writeIcon(objectIcon, w)
+	if err != nil {
+		return wr, err
+	}
+
+	var n int
+	if len(members) == 0 {
+		n, err = w.WriteString(f.punct(f.decorations().EmptyObject))
+	} else {
+		n, err = f.writeInlineOr(func(w *bufio.Writer) (int, error) {
+			return f.writeMembers(members, w, depth)
+		}, w)
+	}
+	return wr + n, err
+}
 
+func (f *Formatter) writeMembers(members []member, w *bufio.Writer, depth int) (int, error) {
 	var wr int
-	n, err := w.WriteString(f.sprintColor(f.BackColor, startMap))
+	n, err := w.WriteString(f.punct(f.decorations().ObjectOpen) + f.keyCountLabel(len(members)))
+	f.openFold()
 	if err != nil {
 		return wr, err
 	}
@@ -207,39 +615,29 @@ func (f *Formatter) marshalMap(m reflect.Value, w *bufio.Writer, depth int) (int
 
 	wr += n
 
-	for _, key := range m.MapKeys() {
-		n, err = f.writeIndent(w, depth+1)
+	for i, m := range members {
+		n, err = f.writeItemStart(w, depth, i)
 		if err != nil {
 			return wr, err
 		}
 
 		wr += n
 
-		n, err = w.WriteString(f.KeyColor.Sprintf("\"%s\": ", key.String()))
+		n, err = f.writeKey(m, w)
 		if err != nil {
 			return wr, err
 		}
 
 		wr += n
 
-		n, err = f.marshalValue(m.MapIndex(key), w, depth+1)
+		n, err = f.marshalMember(m, w, depth+1)
 		if err != nil {
 			return wr, err
 		}
 
 		wr += n
 
-		remaining--
-		if remaining != 0 {
-			n, err = w.WriteString(f.sprintColor(f.BackColor, valueSep))
-			if err != nil {
-				return wr, err
-			}
-
-			wr += n
-		}
-
-		n, err = f.writeObjSep(w)
+		n, err = f.writeItemEnd(w, i, len(members))
 		if err != nil {
 			return wr, err
 		}
@@ -254,7 +652,8 @@ func (f *Formatter) marshalMap(m reflect.Value, w *bufio.Writer, depth int) (int
 
 	wr += n
 
-	n, err = w.WriteString(f.sprintColor(f.BackColor, endMap))
+	n, err = w.WriteString(f.punct(f.decorations().ObjectClose))
+	f.closeFold()
 	if err != nil {
 		return wr, err
 	}
@@ -264,14 +663,89 @@ func (f *Formatter) marshalMap(m reflect.Value, w *bufio.Writer, depth int) (int
 	return wr, nil
 }
 
+// writeKey writes the quoted key of m. Keys of unexported fields are marked
+// with a "~" prefix so they can't be mistaken for real output. With
+// UnquotedKeys, keys that are valid identifiers are written bare.
+func (f *Formatter) writeKey(m member, w *bufio.Writer) (int, error) {
+	key := escapeKey(m)
+	c := f.colorOf(ElementKeys, f.KeyColor)
+	if m.unexported {
+		key = unexportedMark + key
+		c = f.UnexportedColor
+	} else {
+		f.pushKey(m.key)
+		if f.isHighlighted() {
+			c = f.HighlightColor
+		} else if style := f.keyStyle(); style != nil {
+			c = f.colorOf(ElementKeys, style)
+		}
+		f.pop()
+	}
+
+	// Whether the key can go bare depends on the text written, marker
+	// included.
+	d := f.decorations()
+	format := escapePercent(d.KeyOpen) + "%s" + escapePercent(d.KeyClose)
+	if f.UnquotedKeys && isIdentifier(key) {
+		format = "%s"
+	}
+
+	// The separator takes the key's color unless punctuation has a style
+	// of its own.
+	icon := f.icon(keyIcon, c)
+	if f.FaintPunctuation {
+		return w.WriteString(icon + f.sprintfColor(c, format, key) + f.punct(f.keyValueSep()))
+	}
+	return w.WriteString(icon + f.sprintfColor(c, format+escapePercent(f.keyValueSep()), key))
+}
+
+// escapeKey returns the key of m escaped as in a JSON string, without the
+// quotes. Keys from encoding/json output keep their escapes.
+func escapeKey(m member) string {
+	quoted := string(m.rawKey)
+	if quoted == "" {
+		b, _ := json.Marshal(m.key)
+		quoted = string(b)
+	}
+	return quoted[1 : len(quoted)-1]
+}
+
 func (f *Formatter) marshalArray(a reflect.Value, w *bufio.Writer, depth int) (int, error) {
-	if a.Len() == 0 {
-		return w.WriteString(f.sprintColor(f.BackColor, emptyArray))
+	return f.marshalElems(a.Len(), func(i int) node { return node{value: a.Index(i)} }, w, depth)
+}
+
+func (f *Formatter) marshalElems(length int, elem func(i int) node, w *bufio.Writer, depth int) (int, error) {
+	at := func(i int) (int, node) { return i, elem(i) }
+	if f.FilterFunc != nil {
+		kept := f.filterElems(length, elem)
+		length = len(kept)
+		at = func(i int) (int, node) { return kept[i].index, kept[i].node }
+	}
+
+	wr, err := f.writeIcon(arrayIcon, w)
+	if err != nil {
+		return wr, err
+	}
+
+	var n int
+	switch {
+	case length == 0:
+		n, err = w.WriteString(f.punct(f.decorations().EmptyArray))
+	case f.ScalarArraysInline && f.Indent != 0 && allScalars(length, at):
+		n, err = f.writeScalarElems(length, at, w, depth)
+	default:
+		n, err = f.writeInlineOr(func(w *bufio.Writer) (int, error) {
+			return f.writeElems(length, at, w, depth)
+		}, w)
 	}
+	return wr + n, err
+}
 
+func (f *Formatter) writeElems(length int, at func(i int) (int, node), w *bufio.Writer, depth int) (int, error) {
 	var wr int
 
-	n, err := w.WriteString(f.sprintColor(f.BackColor, startArray))
+	n, err := w.WriteString(f.punct(f.decorations().ArrayOpen))
+	f.openFold()
 	if err != nil {
 		return n, err
 	}
@@ -285,23 +759,18 @@ func (f *Formatter) marshalArray(a reflect.Value, w *bufio.Writer, depth int) (i
 
 	wr += n
 
-	for i := 0; i < a.Len(); i++ {
-		n, err = f.writeIndent(w, depth)
-		if err != nil {
-			return wr, err
-		}
-
-		wr += n
-
-		n, err = f.marshalValue(a.Index(i), w, depth+1)
+	for i := 0; i < length; i++ {
+		n, err = f.writeItemStart(w, depth, i)
 		if err != nil {
 			return wr, err
 		}
 
 		wr += n
 
-		if i < a.Len()-1 {
-			n, err = w.WriteString(f.sprintColor(f.BackColor, valueSep))
+		index, el := at(i)
+		if f.ArrayIndexes != IndexOff {
+			last, _ := at(length - 1)
+			n, err = w.WriteString(f.indexLabel(index, last))
 			if err != nil {
 				return wr, err
 			}
@@ -309,7 +778,16 @@ func (f *Formatter) marshalArray(a reflect.Value, w *bufio.Writer, depth int) (i
 			wr += n
 		}
 
-		n, err = f.writeObjSep(w)
+		f.pushIndex(index)
+		n, err = f.marshalNode(el, w, depth+1)
+		f.pop()
+		if err != nil {
+			return wr, err
+		}
+
+		wr += n
+
+		n, err = f.writeItemEnd(w, i, length)
 		if err != nil {
 			return wr, err
 		}
@@ -323,7 +801,8 @@ func (f *Formatter) marshalArray(a reflect.Value, w *bufio.Writer, depth int) (i
 
 	wr += n
 
-	n, err = w.WriteString(f.sprintColor(f.BackColor, endArray))
+	n, err = w.WriteString(f.punct(f.decorations().ArrayClose))
+	f.closeFold()
 	if err != nil {
 		return wr, err
 	}
@@ -334,54 +813,243 @@ func (f *Formatter) marshalArray(a reflect.Value, w *bufio.Writer, depth int) (i
 }
 
 func (f *Formatter) marshalValue(val reflect.Value, w *bufio.Writer, depth int) (int, error) {
-	if val.Kind() == reflect.Pointer {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if e, ok := errorOf(val); ok {
+			return f.marshalError(e, w, depth)
+		}
+		if val.Kind() == reflect.Pointer && !val.IsNil() && val.CanInterface() && marshalsItself(val) {
+			return f.marshalDelegated(val, w, depth)
+		}
 		val = val.Elem()
 	}
 
-	if val.Kind() == reflect.Interface {
-		val = val.Elem()
+	if e, ok := errorOf(val); ok {
+		return f.marshalError(e, w, depth)
+	}
+	// Values read from unexported fields can't marshal themselves and are
+	// walked like any other.
+	if val.IsValid() && val.CanInterface() && marshalsItself(val) {
+		return f.marshalDelegated(val, w, depth)
+	}
+
+	if f.UseStringer && !isSupported(val) {
+		if s, ok := stringer(val); ok {
+			return f.marshalString(s.String(), w)
+		}
 	}
 
-	switch val.Type().Kind() {
+	switch val.Kind() {
 	case reflect.Map:
+		if val.IsNil() {
+			return f.marshalNull(w)
+		}
 		return f.marshalMap(val, w, depth)
 	case reflect.Slice:
+		if val.IsNil() {
+			return f.marshalNull(w)
+		}
+		if isBytes(val.Type()) {
+			return f.marshalString(base64.StdEncoding.EncodeToString(val.Bytes()), w)
+		}
+		return f.marshalArray(val, w, depth)
+	case reflect.Array:
 		return f.marshalArray(val, w, depth)
 	case reflect.String:
+		if val.Type() == numberType {
+			return f.marshalNumber(val.String(), w)
+		}
 		return f.marshalString(val.String(), w)
 	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		var s string
 		if val.CanFloat() {
-			s = strconv.FormatFloat(val.Float(), 'f', -1, 64)
+			s = formatFloat(val.Float(), val.Type().Bits())
 		} else if val.CanInt() {
 			s = strconv.FormatInt(val.Int(), 10)
 		}
-		return w.WriteString(f.sprintColor(f.NumberColor, s))
+		return f.marshalNumber(s, w)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return f.marshalNumber(strconv.FormatUint(val.Uint(), 10), w)
 	case reflect.Bool:
-		return w.WriteString(f.sprintColor(f.BoolColor, strconv.FormatBool(val.Bool())))
+		return f.marshalBool(val.Bool(), w)
 	case reflect.Invalid:
-		return w.WriteString(f.sprintColor(f.NullColor, null)) // nil todo
+		return f.marshalNull(w)
 	case reflect.Struct:
 		return f.marshalStruct(val, w, depth)
 	}
 
-	return 0, nil
+	return f.marshalDelegated(val, w, depth)
+}
+
+// marshalQuoted writes a field with the ,string tag option: its scalar
+// value inside a JSON string. As in encoding/json, values that marshal
+// themselves ignore the option and numbers are quoted as they are.
+func (f *Formatter) marshalQuoted(val reflect.Value, w *bufio.Writer, depth int) (int, error) {
+	for {
+		if val.CanInterface() && marshalsItself(val) {
+			return f.marshalValue(val, w, depth)
+		}
+		if val.Kind() != reflect.Pointer {
+			break
+		}
+		if val.IsNil() {
+			return f.marshalNull(w)
+		}
+		val = val.Elem()
+	}
+
+	var s string
+	c, e := f.StringColor, ElementStrings
+	switch val.Kind() {
+	case reflect.String:
+		if val.Type() == numberType {
+			if s = val.String(); s == "" {
+				s = "0"
+			}
+			c, e = f.NumberColor, ElementNumbers
+			break
+		}
+		quoted, _ := json.Marshal(val.String())
+		s = string(quoted)
+	case reflect.Bool:
+		s = strconv.FormatBool(val.Bool())
+		c, e = f.BoolColor, ElementBools
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(val.Uint(), 10)
+		c, e = f.NumberColor, ElementNumbers
+	case reflect.Float32, reflect.Float64:
+		s = formatFloat(val.Float(), val.Type().Bits())
+		c, e = f.NumberColor, ElementNumbers
+	default:
+		s = strconv.FormatInt(val.Int(), 10)
+		c, e = f.NumberColor, ElementNumbers
+	}
+
+	if !f.TintQuoted {
+		c, e = f.StringColor, ElementStrings
+	}
+	return f.marshalColoredString(s, f.colorOf(e, c), w)
+}
+
+// isSupported reports whether val has a JSON representation of its own.
+// Structs without any exported fields are treated as opaque.
+func isSupported(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.Uintptr, reflect.UnsafePointer:
+		return false
+	case reflect.Struct:
+		t := val.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				return true
+			}
+		}
+		return t.NumField() == 0
+	}
+	return true
+}
+
+func stringer(val reflect.Value) (fmt.Stringer, bool) {
+	if val.CanAddr() && val.Addr().CanInterface() {
+		if s, ok := val.Addr().Interface().(fmt.Stringer); ok {
+			return s, true
+		}
+	}
+	if val.CanInterface() {
+		if s, ok := val.Interface().(fmt.Stringer); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func (f *Formatter) marshalNumber(s string, w *bufio.Writer) (int, error) {
+	c := f.valueColor(ElementNumbers, f.NumberColor, s)
+	return w.WriteString(f.icon(numberIcon, c) + f.sprintColor(c, s))
+}
+
+func (f *Formatter) marshalBool(b bool, w *bufio.Writer) (int, error) {
+	literal, marker := f.decorations().False, emojiFalse
+	if b {
+		literal, marker = f.decorations().True, emojiTrue
+	}
+	c := f.valueColor(ElementBools, f.BoolColor, strconv.FormatBool(b))
+	return w.WriteString(f.icon(boolIcon, c) + f.sprintColor(c, literal) + f.emoji(marker))
+}
+
+func (f *Formatter) marshalNull(w *bufio.Writer) (int, error) {
+	c := f.valueColor(ElementNulls, f.NullColor, null)
+	return w.WriteString(f.icon(nullIcon, c) + f.sprintColor(c, f.decorations().Null) + f.emoji(emojiNull))
 }
 
 func (f *Formatter) marshalString(str string, w *bufio.Writer) (int, error) {
+	var wr int
+	var err error
+	c := f.valueColor(ElementStrings, f.StringColor, str)
+	var target string
+	if f.FileLinks && looksLikePath(str) {
+		target = fileURL(str)
+	} else if f.linkOf != nil {
+		target = f.linkOf(f.currentPath(), str)
+	}
+	if target != "" && !f.DisabledColor {
+		wr, err = w.WriteString(hyperlink(target, f.formatString(str, c)))
+	} else {
+		wr, err = f.marshalColoredString(str, c, w)
+	}
+	if err != nil {
+		return wr, err
+	}
+
+	n, err := f.writeImagePreview(str, w)
+	wr += n
+	if err != nil {
+		return wr, err
+	}
+
+	n, err = f.writeJWTPreview(str, w)
+	wr += n
+	if err != nil {
+		return wr, err
+	}
+
+	n, err = f.writeBase64Preview(str, w)
+	wr += n
+	if err != nil {
+		return wr, err
+	}
+
+	n, err = f.writeQueryStringPreview(str, w)
+	return wr + n, err
+}
+
+func (f *Formatter) marshalColoredString(str string, c color.PrinterFace, w *bufio.Writer) (int, error) {
+	return w.WriteString(f.formatString(str, c))
+}
+
+// formatString quotes and truncates str according to the formatter options
+// and colors it with c.
+func (f *Formatter) formatString(str string, c color.PrinterFace) string {
 	if !f.RawStrings {
 		strBytes, _ := json.Marshal(str)
 		str = string(strBytes)
 	}
 
+	return f.icon(stringIcon, c) + f.truncateString(str, c)
+}
+
+// truncateString cuts str at StringMaxLength and colors it with c.
+func (f *Formatter) truncateString(str string, c color.PrinterFace) string {
 	if f.StringMaxLength != 0 && len(str) >= f.StringMaxLength {
 		str = fmt.Sprintf("%s...", str[0:f.StringMaxLength])
+		f.stats.Truncated++
 	}
 
-	return w.WriteString(f.sprintColor(f.StringColor, str))
+	return f.sprintColor(c, str)
 }
 
 // Marshal JSON data with default options
 func Marshal(w io.Writer, jsonObj interface{}) error {
-	return NewFormatter(w).Encode(jsonObj)
+	f := Default()
+	f.Buffer = bufio.NewWriter(w)
+	return f.Encode(jsonObj)
 }
@@ -1,10 +1,28 @@
 package colorjson_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/olebeck/colorjson"
+	"github.com/xo/terminfo"
 )
-import "github.com/relvacode/colorjson"
 
 func benchmarkMarshall(i int, b *testing.B) {
 	simpleMap := make(map[string]interface{})
@@ -21,3 +39,2284 @@ func benchmarkMarshall(i int, b *testing.B) {
 
 func BenchmarkMarshall(b *testing.B)   { benchmarkMarshall(100, b) }
 func BenchmarkMarshall1k(b *testing.B) { benchmarkMarshall(1000, b) }
+
+func encodePlain(t *testing.T, v reflect.Value) string {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if err := f.EncodeValue(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestEncodeValue(t *testing.T) {
+	type inner struct {
+		hidden []string
+	}
+	v := reflect.ValueOf(inner{hidden: []string{"a"}}).Field(0)
+
+	if got, want := encodePlain(t, v), "[ \"a\" ]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := encodePlain(t, reflect.Value{}), "null"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+const benchDoc = `{
+  "str": "foo",
+  "num": 100,
+  "bool": false,
+  "null": null,
+  "array": ["foo", "bar", "baz", 1, 2, 3],
+  "obj": {"a": 1, "b": 2, "c": {"d": [true, false, null]}}
+}`
+
+func benchObj(b *testing.B) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(benchDoc), &obj); err != nil {
+		b.Fatal(err)
+	}
+	return obj
+}
+
+func BenchmarkEncode(b *testing.B) {
+	obj := benchObj(b)
+	f := colorjson.NewFormatter(ioutil.Discard)
+	f.DisabledColor = true
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		f.Encode(obj)
+	}
+}
+
+func BenchmarkEncodeT(b *testing.B) {
+	obj := benchObj(b)
+	f := colorjson.NewFormatter(ioutil.Discard)
+	f.DisabledColor = true
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		colorjson.EncodeT(f, obj)
+	}
+}
+
+func TestEncodeT(t *testing.T) {
+	obj := map[string]interface{}{
+		"a": []interface{}{1.5, "x", nil, true},
+		"b": []int{1, 2},
+		"c": map[string]interface{}(nil),
+		"d": []interface{}(nil),
+		"e": []string(nil),
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		var want, got bytes.Buffer
+		f := colorjson.NewFormatter(&want)
+		if err := f.Encode(obj[key]); err != nil {
+			t.Fatal(err)
+		}
+		f = colorjson.NewFormatter(&got)
+		if err := colorjson.EncodeT(f, obj[key]); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s: got %q, want %q", key, got.String(), want.String())
+		}
+	}
+}
+
+type opaque struct{ id int }
+
+func (o opaque) String() string { return "opaque" }
+
+type signal chan struct{}
+
+func (s signal) String() string { return "signal" }
+
+func TestUseStringer(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	f.UseStringer = true
+	if err := f.Encode([]interface{}{opaque{1}, make(signal)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "[ \"opaque\", \"signal\" ]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := fmt.Errorf("open config: %w", errors.New("not found"))
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if err := f.Encode(struct{ Err error }{err}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "{ \"Err\": \"open config: not found\" }"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	f.ExpandErrors = true
+	if err := f.Encode(err); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "[ \"open config: not found\", \"not found\" ]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type Base struct {
+	ID   int
+	Name string
+}
+
+type Meta struct {
+	Name string `json:"name"`
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	v := struct {
+		Base
+		*Meta
+		Kind string
+	}{Base: Base{ID: 1, Name: "base"}, Meta: &Meta{Name: "meta"}, Kind: "k"}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	want := `{ "ID": 1, "Name": "base", "name": "meta", "Kind": "k" }`
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnexportedFields(t *testing.T) {
+	v := struct {
+		Public string
+		secret string
+	}{"a", "b"}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{ "Public": "a" }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	f.IncludeUnexported = true
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{ "Public": "a", "~secret": "b" }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type inner struct {
+		X int `json:"x,omitempty"`
+	}
+	type doc struct {
+		S   string            `json:"s,omitempty"`
+		N   int               `json:"n,omitempty"`
+		U   uint              `json:"u,omitempty"`
+		F   float64           `json:"f,omitempty"`
+		B   bool              `json:"b,omitempty"`
+		P   *int              `json:"p,omitempty"`
+		I   interface{}       `json:"i,omitempty"`
+		L   []int             `json:"l,omitempty"`
+		M   map[string]string `json:"m,omitempty"`
+		In  inner             `json:"in,omitempty"`
+		Set int               `json:"set,omitempty"`
+	}
+
+	got := plain(t, doc{Set: 1}, nil)
+	if want := `{ "in": {}, "set": 1 }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, []doc{{}, {In: inner{X: 1}}}, func(f *colorjson.Formatter) { f.IncludeKeys = []string{"x"} })
+	if want := `[ {}, { "in": { "x": 1 } } ]`; got != want {
+		t.Errorf("include: got %q, want %q", got, want)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	v := struct {
+		B  int
+		A  int
+		ID int `colorjson:"order=1"`
+	}{}
+
+	tests := []struct {
+		order colorjson.FieldOrder
+		want  string
+	}{
+		{colorjson.DeclarationOrder, `{ "B": 0, "A": 0, "ID": 0 }`},
+		{colorjson.AlphabeticalOrder, `{ "A": 0, "B": 0, "ID": 0 }`},
+		{colorjson.TagPriorityOrder, `{ "ID": 0, "B": 0, "A": 0 }`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		f := colorjson.NewFormatter(&buf)
+		f.DisabledColor = true
+		f.FieldOrder = tt.order
+		if err := f.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("order %d: got %q, want %q", tt.order, got, tt.want)
+		}
+	}
+
+	got := plain(t, struct{ AZ, Ab int }{}, func(f *colorjson.Formatter) {
+		f.FieldOrder = colorjson.AlphabeticalOrder
+		f.KeyTransform = colorjson.SnakeCase
+	})
+	if want := `{ "ab": 0, "az": 0 }`; got != want {
+		t.Errorf("transformed: got %q, want %q", got, want)
+	}
+}
+
+// plain encodes v without colors after applying configure to the formatter.
+func plain(t *testing.T, v interface{}, configure func(f *colorjson.Formatter)) string {
+	t.Helper()
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if configure != nil {
+		configure(f)
+	}
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestKeyTransform(t *testing.T) {
+	for in, want := range map[string]string{
+		"UserID":        "user_id",
+		"HTTPServer":    "http_server",
+		"already_snake": "already_snake",
+		"Version2Name":  "version2_name",
+	} {
+		if got := colorjson.SnakeCase(in); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got, want := colorjson.CamelCase("HTTPServerID"), "httpServerId"; got != want {
+		t.Errorf("CamelCase = %q, want %q", got, want)
+	}
+	if got, want := colorjson.KebabCase("CreatedAt"), "created-at"; got != want {
+		t.Errorf("KebabCase = %q, want %q", got, want)
+	}
+
+	v := struct {
+		CreatedAt int
+		UserID    int `json:"UID"`
+	}{}
+	got := plain(t, v, func(f *colorjson.Formatter) { f.KeyTransform = colorjson.SnakeCase })
+	if want := `{ "created_at": 0, "UID": 0 }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnquotedKeys(t *testing.T) {
+	v := map[string]interface{}{"name": 1}
+	got := plain(t, v, func(f *colorjson.Formatter) { f.UnquotedKeys = true })
+	if want := `{ name: 1 }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	v = map[string]interface{}{"content-type": 1}
+	got = plain(t, v, func(f *colorjson.Formatter) { f.UnquotedKeys = true })
+	if want := `{ "content-type": 1 }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	type user struct {
+		Name string
+		name string
+	}
+	got = plain(t, user{"a", "b"}, func(f *colorjson.Formatter) {
+		f.UnquotedKeys = true
+		f.IncludeUnexported = true
+	})
+	if want := `{ Name: "a", "~name": "b" }`; got != want {
+		t.Errorf("unexported: got %q, want %q", got, want)
+	}
+}
+
+func TestKeyFilters(t *testing.T) {
+	v := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":     "web",
+			"trace_id": "abc",
+		},
+		"status": map[string]interface{}{"phase": "Running"},
+	}
+
+	got := plain(t, v, func(f *colorjson.Formatter) { f.IncludeKeys = []string{"name"} })
+	if want := `{ "spec": { "name": "web" } }`; got != want {
+		t.Errorf("include: got %q, want %q", got, want)
+	}
+
+	got = plain(t, v["spec"], func(f *colorjson.Formatter) { f.ExcludeKeys = []string{"*_id"} })
+	if want := `{ "name": "web" }`; got != want {
+		t.Errorf("exclude: got %q, want %q", got, want)
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	v := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":          "web",
+					"managedFields": []interface{}{"x"},
+				},
+			},
+		},
+	}
+
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.FilterRules = []colorjson.FilterRule{{Path: "$.items[*].metadata.managedFields"}}
+	})
+	if want := `{ "items": [ { "metadata": { "name": "web" } } ] }`; got != want {
+		t.Errorf("path: got %q, want %q", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.FilterRules = []colorjson.FilterRule{{MinDepth: 4, Keys: []string{"name"}}}
+	})
+	if want := `{ "items": [ { "metadata": { "name": "web" } } ] }`; got != want {
+		t.Errorf("depth: got %q, want %q", got, want)
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	v := map[string]interface{}{
+		"tags": []interface{}{"a", "secret", "b"},
+	}
+	filter := func(path string, v interface{}) bool { return v != "secret" }
+
+	got := plain(t, v, func(f *colorjson.Formatter) { f.FilterFunc = filter })
+	if want := `{ "tags": [ "a", "b" ] }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.FilterFunc = func(path string, v interface{}) bool { return path != "$.tags[1]" }
+		f.ShowFiltered = true
+	})
+	if want := `{ "tags": [ "a", …filtered, "b" ] }`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{"items": [
+		{"name": "a", "status": "ok", "tries": 1},
+		{"name": "b", "status": "failed", "tries": 3},
+		{"name": "c", "status": "failed", "tries": 1}
+	]}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []interface{}
+	}{
+		{`.items[0].name`, []interface{}{"a"}},
+		{`.items[-1].name`, []interface{}{"c"}},
+		{`.items[] | select(.status=="failed") | .name`, []interface{}{"b", "c"}},
+		{`.items[] | select(.status == "failed" and .tries > 1) | .name`, []interface{}{"b"}},
+		{`.items[] | select(.tries >= 3 or .name == "a") | .name`, []interface{}{"a", "b"}},
+	}
+	for _, tt := range tests {
+		q, err := colorjson.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		got, err := q.Run(doc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if _, err := colorjson.ParseQuery(`.items[`); err == nil {
+		t.Error("expected an error for an unterminated index")
+	}
+}
+
+func TestFoldMarkers(t *testing.T) {
+	v := map[string]interface{}{"a": map[string]interface{}{"b": 1}}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.FoldMarkers = true
+	})
+	want := "{ // {{{\n  \"a\": { // {{{\n    \"b\": 1\n  } // }}}\n} // }}}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestImagePreview(t *testing.T) {
+	png := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n0000"))
+
+	got := plain(t, []string{png}, func(f *colorjson.Formatter) { f.ImagePreview = colorjson.ImagePreviewAnnotate })
+	if want := `[ "` + png + `" /* image/png, 12 B */ ]`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, []string{"not an image"}, func(f *colorjson.Formatter) { f.ImagePreview = colorjson.ImagePreviewAnnotate })
+	if want := `[ "not an image" ]`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	large := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n" + strings.Repeat("0", 2040)))
+	got = plain(t, []string{large, large[:len(large)-4] + "!!!!"}, func(f *colorjson.Formatter) {
+		f.ImagePreview = colorjson.ImagePreviewKitty
+		f.ImagePreviewMaxBytes = 1024
+	})
+	if want := `[ "` + large + `" /* image/png, 2.0 KB */, "` + large[:len(large)-4] + `!!!!" ]`; got != want {
+		t.Errorf("over the limit: got %q, want %q", got, want)
+	}
+}
+
+func TestFileLinks(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.FileLinks = true
+	if err := f.Encode([]string{"/var/log/app.log", "not/a path"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Count(got, "\x1b]8;;file://") != 1 || !strings.Contains(got, "/var/log/app.log\x1b\\") {
+		t.Errorf("expected a single file link, got %q", got)
+	}
+}
+
+func TestStreamLevels(t *testing.T) {
+	in := `{"level":"error","msg":"boom","code":2}
+{"level":30,"msg":"ok"}
+{"msg":"no level"}
+`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.LevelKey = "level"
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], color.FgRed.Sprint("┃ ")) {
+		t.Errorf("error record not marked red: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], color.FgGreen.Sprint("┃ ")) {
+		t.Errorf("info record not marked green: %q", lines[1])
+	}
+	if strings.Contains(lines[2], "┃") {
+		t.Errorf("record without level was marked: %q", lines[2])
+	}
+	if plain := color.ClearCode(lines[0]); plain != `┃ { "level": "error", "msg": "boom", "code": 2 }` {
+		t.Errorf("key order not preserved: %q", plain)
+	}
+}
+
+func TestHighlightFromEnv(t *testing.T) {
+	t.Setenv(colorjson.HighlightEnv, "trace_id, user.email")
+	if keys := colorjson.NewFormatter(nil).HighlightKeys; keys != nil {
+		t.Errorf("NewFormatter read the environment: %q", keys)
+	}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.HighlightKeys = colorjson.Default().HighlightKeys
+	f.HighlightColor = color.FgRed
+	v := map[string]interface{}{
+		"user":  map[string]interface{}{"email": "a@b.c"},
+		"email": "x",
+	}
+	if err := f.Encode(v["user"]); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Contains(got, color.FgRed.Sprint(`"email": `)) {
+		t.Errorf("email outside user highlighted: %q", got)
+	}
+
+	buf.Reset()
+	if err := f.Encode(map[string]interface{}{"user": v["user"]}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, color.FgRed.Sprint(`"email": `)) {
+		t.Errorf("user.email not highlighted: %q", got)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	v := map[string]interface{}{"b": 1, "c": 2, "a": map[string]int{"z": 1, "y": 2}}
+	got := plain(t, v, func(f *colorjson.Formatter) { f.SortKeys = true })
+	want := `{ "a": { "y": 2, "z": 1 }, "b": 1, "c": 2 }`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(colorjson.ThemeEnv, "monokai")
+	t.Setenv(colorjson.IndentEnv, "4")
+	t.Setenv(colorjson.SortKeysEnv, "true")
+
+	f := colorjson.FromEnv(nil)
+	if f.Indent != 4 || !f.SortKeys {
+		t.Errorf("got indent %d, sort keys %v", f.Indent, f.SortKeys)
+	}
+	if f.KeyColor != colorjson.Themes["monokai"].Key {
+		t.Errorf("got key color %v, want the monokai theme", f.KeyColor)
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	f := colorjson.NewFormatter(nil)
+	f.DisabledColor = true
+	f.Indent = 2
+	colorjson.SetDefault(f)
+	defer colorjson.SetDefault(nil)
+
+	f.Indent = 4
+	var buf bytes.Buffer
+	if err := colorjson.Marshal(&buf, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"a\": 1\n}"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if colorjson.Default().Indent != 2 {
+		t.Error("changing f after SetDefault changed the default")
+	}
+}
+
+func TestEncodeOptions(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.Indent = 2
+
+	if err := f.Encode(map[string]int{"a": 1}, colorjson.Compact(), colorjson.NoColor()); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{ "a": 1 }`; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if f.Indent != 2 || f.DisabledColor {
+		t.Error("options changed the formatter")
+	}
+}
+
+func TestTee(t *testing.T) {
+	var colored, plain bytes.Buffer
+	f := colorjson.NewFormatter(nil)
+	f.FileLinks = true
+	f.Tee(&colored, &plain)
+
+	if err := f.Encode([]interface{}{"./go.mod", 1, nil}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[ "./go.mod", 1, null ]`; plain.String() != want {
+		t.Errorf("got plain %q, want %q", plain.String(), want)
+	}
+	if !strings.Contains(colored.String(), "\x1b[") {
+		t.Errorf("colored output %q has no colors", colored.String())
+	}
+}
+
+func TestOnLine(t *testing.T) {
+	var lines []string
+	f := colorjson.NewFormatter(nil)
+	f.Indent = 2
+	f.RawStrings = true
+	f.OnLine(func(line []byte) { lines = append(lines, string(line)) })
+
+	if err := f.Encode(map[string]string{"a": "x\ny"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if strings.Count(line, "\x1b[") != 2*strings.Count(line, "\x1b[0m") {
+			t.Errorf("line %q leaves a color set", line)
+		}
+	}
+	if got := color.ClearCode(strings.Join(lines, "\n")); got != "{\n  \"a\": x\ny\n}" {
+		t.Errorf("got %q", got)
+	}
+}
+
+type flushCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *flushCounter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+func TestAutoFlushEvery(t *testing.T) {
+	var out flushCounter
+	f := colorjson.NewFormatter(&out)
+	f.DisabledColor = true
+	f.Indent = 2
+	f.AutoFlushEvery = 1
+
+	if err := f.Encode([]int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if out.writes < 4 {
+		t.Errorf("got %d writes, want one per line", out.writes)
+	}
+}
+
+func TestOnProgress(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+
+	var calls, lastBytes, lastNodes int
+	f.OnProgress = func(bytesWritten, nodesVisited int) {
+		calls++
+		lastBytes, lastNodes = bytesWritten, nodesVisited
+	}
+
+	if err := f.Encode(make([]int, 2500)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+	if lastBytes != buf.Len() || lastNodes != 2501 {
+		t.Errorf("got %d bytes, %d nodes, want %d bytes, 2501 nodes", lastBytes, lastNodes, buf.Len())
+	}
+}
+
+func TestEncodeStats(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.StringMaxLength = 3
+	f.ExcludeKeys = []string{"secret"}
+
+	v := map[string]interface{}{
+		"name":   "a long name",
+		"secret": "x",
+		"tags":   []string{"ab", "cd"},
+	}
+	stats, err := f.EncodeStats(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := colorjson.Stats{Bytes: buf.Len(), Nodes: 5, MaxDepth: 2, Truncated: 3, Elided: 1}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+}
+
+type escaped struct{}
+
+func (escaped) MarshalJSON() ([]byte, error) {
+	return []byte(`"café \/"`), nil
+}
+
+func TestStdlibCompat(t *testing.T) {
+	v := struct {
+		Name    string  `json:"name"`
+		Empty   string  `json:"empty,omitempty"`
+		HTML    string  `json:"html"`
+		Float   float64 `json:"float"`
+		Escaped escaped `json:"escaped"`
+	}{Name: "x", HTML: "<a&b>", Float: 1e21, Escaped: escaped{}}
+
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.StdlibCompat = true
+	})
+	want, _ := json.MarshalIndent(v, "", "  ")
+	if got != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	doc := map[string]interface{}{"a": []interface{}{1, "x", map[string]int{}}, "b": v}
+	got = plain(t, doc, func(f *colorjson.Formatter) { f.StdlibCompat = true })
+	if want, _ := json.Marshal(doc); got != string(want) {
+		t.Errorf("compact: got %s, want %s", got, want)
+	}
+}
+
+func TestEscapedKeys(t *testing.T) {
+	v := map[string]int{"a\"b\n": 1, "<k>": 2}
+
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.StdlibCompat = true
+	})
+	want, _ := json.MarshalIndent(v, "", "  ")
+	if got != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) { f.SortKeys = true })
+	if want := `{ "\u003ck\u003e": 2, "a\"b\n": 1 }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.DisabledColor = true
+	if err := s.Copy(strings.NewReader(`{"a\"b\u0001":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{ "a\"b\u0001": 1 }` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDelegateUnknownTypes(t *testing.T) {
+	v := map[string]interface{}{
+		"uint":  uint8(7),
+		"time":  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		"raw":   json.RawMessage(`{"b":[1]}`),
+		"bytes": []byte("hi"),
+		"ints":  map[int]string{3: "c"},
+		"nil":   []int(nil),
+	}
+	got := plain(t, v, func(f *colorjson.Formatter) { f.SortKeys = true })
+	want := `{ "bytes": "aGk=", "ints": { "3": "c" }, "nil": null, "raw": { "b": [ 1 ] }, "time": "2024-05-01T12:00:00Z", "uint": 7 }`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for _, v := range []interface{}{
+		map[string]interface{}{"complex": complex(1, 2)},
+		[]interface{}{make(chan int)},
+		map[float64]int{1: 1},
+	} {
+		if err := colorjson.NewFormatter(ioutil.Discard).Encode(v); err == nil {
+			t.Errorf("%T: expected an error", v)
+		}
+	}
+}
+
+// ptrMarshaler marshals itself only through a pointer.
+type ptrMarshaler struct{ n int }
+
+func (p *ptrMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+func TestUnexportedScalars(t *testing.T) {
+	type s struct {
+		n   uint
+		at  time.Time
+		Pub uint16
+	}
+	got := plain(t, s{n: 7, Pub: 8}, func(f *colorjson.Formatter) { f.IncludeUnexported = true })
+	if !strings.HasPrefix(got, `{ "~n": 7, "~at": { `) || !strings.HasSuffix(got, `"Pub": 8 }`) {
+		t.Errorf("got %q", got)
+	}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.DisabledColor = true
+	if err := f.EncodeValue(reflect.ValueOf(s{n: 7}).Field(0)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "7" {
+		t.Errorf("EncodeValue: got %q, want 7", got)
+	}
+}
+
+func TestPointerMarshaler(t *testing.T) {
+	v := &struct{ P ptrMarshaler }{}
+	want, _ := json.Marshal(v)
+	if got := plain(t, v, nil); strings.ReplaceAll(got, " ", "") != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// level is an int that marshals itself as text.
+type level int
+
+func (level) MarshalText() ([]byte, error) { return []byte("high"), nil }
+
+// ptrLevel is an int that marshals itself through a pointer.
+type ptrLevel int
+
+func (*ptrLevel) MarshalJSON() ([]byte, error) { return []byte(`"low"`), nil }
+
+func TestStringTagOption(t *testing.T) {
+	n := 3
+	v := &struct {
+		Count  int         `json:"count,string"`
+		Ptr    *int        `json:"ptr,string"`
+		OK     bool        `json:"ok,string"`
+		Name   string      `json:"name,string"`
+		Nested []int       `json:"nested,string"`
+		Number json.Number `json:"number,string"`
+		Empty  json.Number `json:"empty,string"`
+		Float  float32     `json:"float,string"`
+		Uint   uint8       `json:"uint,string"`
+		Level  level       `json:"level,string"`
+		Low    ptrLevel    `json:"low,string"`
+		NilLow *ptrLevel   `json:"nil_low,string"`
+	}{Count: 42, Ptr: &n, OK: true, Name: "x", Nested: []int{1}, Number: "1.5e3", Float: 0.1, Uint: 7}
+
+	got := plain(t, v, nil)
+	want, _ := json.Marshal(v)
+	if strings.ReplaceAll(got, " ", "") != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.TintQuoted = true
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), f.NumberColor.Sprint(`"42"`)) {
+		t.Errorf("got %q, want the count in the number color", buf.String())
+	}
+}
+
+type tagInner struct {
+	Name string
+	ID   int `json:"id"`
+}
+
+type tagA struct{ Name string }
+type tagB struct{ Name string }
+
+type tagConflicts struct {
+	tagA
+	tagB
+	tagInner
+	Skipped string `json:"-"`
+	Dash    string `json:"-,"`
+	Lower   string `json:"name2"`
+	Upper   string `json:"NAME2"`
+}
+
+func TestTagResolution(t *testing.T) {
+	v := tagConflicts{Skipped: "x", Dash: "d", Lower: "l", Upper: "u"}
+	v.tagInner.Name = "inner"
+	v.ID = 1
+
+	got := plain(t, v, nil)
+	want, _ := json.Marshal(v)
+	if strings.ReplaceAll(got, " ", "") != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestInlineShort(t *testing.T) {
+	v := map[string]interface{}{
+		"point": map[string]int{"x": 1},
+		"tags":  []string{"a", "b"},
+		"long":  map[string]string{"a": "a long string"},
+	}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.SortKeys = true
+		f.InlineShort = 20
+	})
+	want := "{\n  \"long\": {\n    \"a\": \"a long string\"\n  },\n  \"point\": { \"x\": 1 },\n  \"tags\": [ \"a\", \"b\" ]\n}"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestScalarArraysInline(t *testing.T) {
+	v := map[string]interface{}{"v": []int{1, 2, 3, 4, 5, 6, 7, 8}}
+	configure := func(width int) func(f *colorjson.Formatter) {
+		return func(f *colorjson.Formatter) {
+			f.Indent = 2
+			f.ScalarArraysInline = true
+			f.MaxWidth = width
+		}
+	}
+
+	if got, want := plain(t, v, configure(0)), "{\n  \"v\": [ 1, 2, 3, 4, 5, 6, 7, 8 ]\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := plain(t, v, configure(15)), "{\n  \"v\": [\n    1, 2, 3, 4,\n    5, 6, 7, 8\n  ]\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := colorjson.NewEncoder(&buf)
+	enc.DisabledColor = true
+
+	for _, v := range []interface{}{[]int{1}, map[string]bool{"ok": true}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "[ 1 ]\n{ \"ok\": true }\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCommaStyles(t *testing.T) {
+	v := map[string]interface{}{"a": 1, "b": []int{2, 3}}
+	style := func(trailing, commaFirst bool) func(f *colorjson.Formatter) {
+		return func(f *colorjson.Formatter) {
+			f.Indent = 2
+			f.SortKeys = true
+			f.NonStrict = true
+			f.TrailingCommas = trailing
+			f.CommaFirst = commaFirst
+		}
+	}
+
+	if got, want := plain(t, v, style(true, false)), "{\n  \"a\": 1,\n  \"b\": [\n    2,\n    3,\n  ],\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := plain(t, v, style(false, true)), "{\n  \"a\": 1\n, \"b\": [\n    2\n  , 3\n  ]\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	strict := plain(t, v, func(f *colorjson.Formatter) {
+		style(true, true)(f)
+		f.NonStrict = false
+	})
+	if strings.Contains(strict, ",\n}") || strings.Contains(strict, "\n,") {
+		t.Errorf("got %q, want strict JSON", strict)
+	}
+}
+
+func TestSeparators(t *testing.T) {
+	v := map[string]interface{}{"a": 1, "b": []int{2, 3}}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.SortKeys = true
+		f.KeyValueSeparator = ":"
+		f.ElementSeparator = ","
+	})
+	if want := `{ "a":1,"b":[ 2,3 ] }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.SortKeys = true
+		f.KeyValueSeparator = " : "
+		f.ElementSeparator = " ; "
+	})
+	if want := "{\n  \"a\" : 1;\n  \"b\" : [\n    2;\n    3\n  ]\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecorations(t *testing.T) {
+	v := map[string]interface{}{"a": []interface{}{true, nil}, "b": map[string]int{}}
+	dialect := func(d *colorjson.Decorations) func(f *colorjson.Formatter) {
+		return func(f *colorjson.Formatter) {
+			f.SortKeys = true
+			f.Decorations = d
+		}
+	}
+
+	if got, want := plain(t, v, dialect(&colorjson.PythonDecorations)), `{'a': [True, None], 'b': {}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := plain(t, v, dialect(&colorjson.LuaDecorations)), `{ ["a"] = { true, nil }, ["b"] = {} }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	got := plain(t, map[string]int{"a": 1}, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.Frame = true
+		f.FrameTitle = "GET /"
+	})
+	want := "╭─ GET / ──╮\n" +
+		"│ {        │\n" +
+		"│   \"a\": 1 │\n" +
+		"│ }        │\n" +
+		"╰──────────╯"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFaintPunctuation(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.BackColor = color.FgWhite
+	f.FaintPunctuation = true
+
+	if err := f.Encode(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, punct := range []string{"{", ": ", "}"} {
+		if want := "\x1b[37;2m" + punct + "\x1b[0m"; !strings.Contains(buf.String(), want) {
+			t.Errorf("got %q, want %q faint", buf.String(), punct)
+		}
+	}
+}
+
+func TestStyle(t *testing.T) {
+	s := colorjson.Style{Color: color.FgWhite, Background: color.BgRed, Bold: true, Italic: true}
+	if got, want := s.String(), "37;41;1;3"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.NullColor = colorjson.Style{Italic: true}
+	if err := f.Encode([]interface{}{nil}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[3mnull\x1b[0m") {
+		t.Errorf("got %q, want an italic null", buf.String())
+	}
+}
+
+func TestKeyStyles(t *testing.T) {
+	deprecated := colorjson.Style{Strikethrough: true}
+	required := colorjson.Style{Underline: true}
+
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.KeyStyles = map[string]color.PrinterFace{
+		"legacy_*":  deprecated,
+		"user.id":   required,
+		"*.id":      deprecated,
+		"unrelated": deprecated,
+	}
+	v := map[string]interface{}{"legacy_name": 1, "user": map[string]int{"id": 2}}
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{deprecated.Sprint(`"legacy_name": `), required.Sprint(`"id": `), f.KeyColor.Sprint(`"user": `)} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("got %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestHexTheme(t *testing.T) {
+	colorjson.SetTermLevel(t, terminfo.ColorLevelMillions)
+	theme, err := colorjson.HexTheme{Key: "#ff0080", Null: "#abc"}.Theme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := theme.Key.String(), "38;2;255;0;128"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := theme.Null.String(), "38;2;170;187;204"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if theme.String != nil {
+		t.Errorf("got %v for an unset color", theme.String)
+	}
+
+	if _, err := (colorjson.HexTheme{Key: "#12345"}).Theme(); err == nil {
+		t.Error("got no error for an invalid color")
+	}
+
+	colorjson.SetTermLevel(t, terminfo.ColorLevelHundreds)
+	if got, want := theme.Key.String(), color.C256(color.RgbTo256(255, 0, 128)).String(); got != want {
+		t.Errorf("256 colors: got %q, want %q", got, want)
+	}
+	colorjson.SetTermLevel(t, terminfo.ColorLevelBasic)
+	if got, want := theme.Key.String(), strconv.Itoa(int(color.RgbToAnsi(255, 0, 128, false))); got != want {
+		t.Errorf("16 colors: got %q, want %q", got, want)
+	}
+}
+
+func TestDefaultTheme(t *testing.T) {
+	if got := colorjson.DefaultTheme(colorjson.BackgroundLight); got != colorjson.Themes["light"] {
+		t.Errorf("got %v for a light background", got)
+	}
+	for _, b := range []colorjson.Background{colorjson.BackgroundDark, colorjson.BackgroundUnknown} {
+		if got := colorjson.DefaultTheme(b); got != colorjson.Themes["default"] {
+			t.Errorf("got %v for background %v", got, b)
+		}
+	}
+}
+
+func TestThemeFor(t *testing.T) {
+	if got, _ := colorjson.ThemeFor("monokai", colorjson.BackgroundLight); !reflect.DeepEqual(got, colorjson.Themes["monokai-light"]) {
+		t.Errorf("got %v for monokai on a light background", got)
+	}
+	if got, _ := colorjson.ThemeFor("jq-light", colorjson.BackgroundDark); !reflect.DeepEqual(got, colorjson.Themes["jq"]) {
+		t.Errorf("got %v for jq-light on a dark background", got)
+	}
+	if got, _ := colorjson.ThemeFor("vivid", colorjson.BackgroundUnknown); !reflect.DeepEqual(got, colorjson.Themes["vivid"]) {
+		t.Errorf("got %v for vivid on an unknown background", got)
+	}
+	if _, ok := colorjson.ThemeFor("nope", colorjson.BackgroundLight); ok {
+		t.Error("found a theme that doesn't exist")
+	}
+}
+
+func TestDetectBackground(t *testing.T) {
+	tests := []struct {
+		override, colorfgbg string
+		want                colorjson.Background
+	}{
+		{"", "", colorjson.BackgroundUnknown},
+		{"", "15;0", colorjson.BackgroundDark},
+		{"", "0;default;15", colorjson.BackgroundLight},
+		{"", "0;7", colorjson.BackgroundLight},
+		{"dark", "0;15", colorjson.BackgroundDark},
+		{"light", "", colorjson.BackgroundLight},
+	}
+	t.Setenv("TERM_PROGRAM", "")
+	for _, tt := range tests {
+		t.Setenv(colorjson.BackgroundEnv, tt.override)
+		t.Setenv("COLORFGBG", tt.colorfgbg)
+		if got := colorjson.DetectBackground(); got != tt.want {
+			t.Errorf("DetectBackground() with %q, %q = %v, want %v", tt.override, tt.colorfgbg, got, tt.want)
+		}
+	}
+}
+
+func TestThemeValidate(t *testing.T) {
+	theme := colorjson.Theme{
+		Key:    colorjson.Style{Color: color.FgBlue, Bold: true},
+		String: color.FgGreen,
+	}
+
+	fixed, warnings := theme.Validate(colorjson.BackgroundDark)
+	if len(warnings) != 1 || warnings[0].Element != "Key" {
+		t.Fatalf("got warnings %v, want one for Key", warnings)
+	}
+	if warnings[0].Ratio >= 3 {
+		t.Errorf("got ratio %.2f for blue on black", warnings[0].Ratio)
+	}
+	key, ok := fixed.Key.(colorjson.Style)
+	if !ok || !key.Bold || key.Color == color.FgBlue {
+		t.Errorf("got key %#v, want a lighter bold color", fixed.Key)
+	}
+	if _, again := fixed.Validate(colorjson.BackgroundDark); len(again) != 0 {
+		t.Errorf("adjusted theme still has warnings %v", again)
+	}
+
+	if _, warnings := colorjson.Themes["light"].Validate(colorjson.BackgroundLight); len(warnings) != 0 {
+		t.Errorf("light theme has warnings %v on a light background", warnings)
+	}
+	if _, warnings := theme.Validate(colorjson.BackgroundUnknown); warnings != nil {
+		t.Errorf("got warnings %v for an unknown background", warnings)
+	}
+}
+
+func TestForPalette(t *testing.T) {
+	theme := colorjson.Theme{
+		Key:    colorjson.Style{Color: color.FgBlue, Bold: true},
+		String: color.FgGreen,
+		Null:   color.New(color.FgBlue, color.OpItalic),
+	}
+
+	got := theme.ForPalette(colorjson.PaletteWindowsTerminal)
+	if key := got.Key.(colorjson.Style); key.Color != color.FgLightBlue || !key.Bold {
+		t.Errorf("got key %#v", got.Key)
+	}
+	if got.String != color.FgGreen {
+		t.Errorf("got string %#v", got.String)
+	}
+	if null := got.Null.(color.Style); null[0] != color.FgLightBlue || null[1] != color.OpItalic {
+		t.Errorf("got null %#v", got.Null)
+	}
+	if theme.Null.(color.Style)[0] != color.FgBlue {
+		t.Error("ForPalette changed the original theme")
+	}
+}
+
+func TestDetectPalette(t *testing.T) {
+	t.Setenv("WT_SESSION", "")
+	t.Setenv("TERM_PROGRAM", "Apple_Terminal")
+	if got := colorjson.DetectPalette(); got != colorjson.PaletteAppleTerminal {
+		t.Errorf("got %v, want PaletteAppleTerminal", got)
+	}
+
+	f := colorjson.NewFormatter(nil)
+	if f.BackColor != color.FgBlack {
+		t.Errorf("got back color %#v on Terminal.app", f.BackColor)
+	}
+
+	t.Setenv(colorjson.PaletteEnv, "off")
+	if f := colorjson.NewFormatter(nil); f.BackColor != color.FgWhite {
+		t.Errorf("got back color %#v with adjustments off", f.BackColor)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+
+	sink, err := colorjson.NewFileSink(filepath.Join(dir, "dump"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := colorjson.NewFormatter(sink).Encode([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sink.Path()); !os.IsNotExist(err) {
+		t.Errorf("%s exists before the sink is closed", sink.Path())
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "dump.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[ 1, 2 ]"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+
+	colored, err := colorjson.NewFileSink(filepath.Join(dir, "dump"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colored.Write([]byte("\x1b[32m1\x1b[0m"))
+	if err := colored.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "dump.ansi")); string(data) != "\x1b[32m1\x1b[0m" {
+		t.Errorf("got %q in the .ansi file", data)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("got %d files, want no temporary files left", len(entries))
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	var buf bytes.Buffer
+	v := map[string]interface{}{"a": []int{1}, "b": "<x>", "c": map[string]int{}}
+	if err := colorjson.ExportHTML(&buf, v, colorjson.Themes["default"], colorjson.HTMLLineNumbers()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`.string { color: #`,
+		`<details open><summary><span class="line" data-fold="…}"><span class="ln">1</span><span class="punct">{</span></span>`,
+		`<span class="line" data-fold="…],"><span class="ln">2</span>  <span class="key">"a"</span><span class="punct">: </span><span class="punct">[</span>`,
+		`<span class="string">"&lt;x&gt;"</span><span class="punct">,</span>`,
+		`<span class="punct">{}</span></span>`,
+		`<span class="ln">7</span><span class="punct">}</span></span>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestANSIToHTML(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain <&>", "plain &lt;&amp;&gt;"},
+		{"\x1b[32m\"a\"\x1b[0m: 1", `<span style="color: #1dc121;">"a"</span>: 1`},
+		{"\x1b[1;38;5;28mx\x1b[22my\x1b[m", `<span style="color: #008700; font-weight: bold;">x</span><span style="color: #008700;">y</span>`},
+		{"\x1b[48;2;1;2;3;3mx\x1b[0m", `<span style="background: #010203; font-style: italic;">x</span>`},
+		{"\x1b]8;;https://x.test/a?b&c\x1b\\a\x1b]8;;\x1b\\", `<a href="https://x.test/a?b&amp;c">a</a>`},
+		{"\x1b]8;;JavaScript:alert(1)\x1b\\a\x1b]8;;\x1b\\", "a"},
+		{"\x1b]8;;file:///etc/passwd\x1b\\a\x1b]8;;\x1b\\", "a"},
+		{"x \x1b_Ga=T,f=100;AAAA\x1b\\", "x "},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := colorjson.ANSIToHTML(strings.NewReader(tt.in), &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("ANSIToHTML(%q) = %q, want %q", tt.in, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var out bytes.Buffer
+	rec := colorjson.NewRecorder(&out)
+	rec.Title = "demo"
+
+	s := colorjson.NewStream(rec)
+	s.DisabledColor = true
+	if err := s.Copy(strings.NewReader(`{"a":"é"} [1]`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{ \"a\": \"é\" }\n[ 1 ]\n"; out.String() != want {
+		t.Errorf("passed on %q, want %q", out.String(), want)
+	}
+	// Split a rune between writes.
+	rec.Write([]byte("\xc3"))
+	rec.Write([]byte("\xa9\n"))
+
+	var cast bytes.Buffer
+	if err := rec.WriteCast(&cast); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(cast.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header and 3 events:\n%s", len(lines), cast.String())
+	}
+	if !strings.HasPrefix(lines[0], `{"version":2,"width":80,"height":24,`) || !strings.HasSuffix(lines[0], `"title":"demo"}`) {
+		t.Errorf("got header %s", lines[0])
+	}
+
+	var event []interface{}
+	if err := json.Unmarshal([]byte(lines[3]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if len(event) != 3 || event[1] != "o" || event[2] != "é\r\n" {
+		t.Errorf("got event %v", event)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	t.Setenv(colorjson.HighlightEnv, "a")
+	v := map[string]interface{}{"c": 0.1, "a": []int{1}, "b": struct{ Y, X int }{}, "d": "./go.mod"}
+
+	var first string
+	for i := 0; i < 20; i++ {
+		var buf bytes.Buffer
+		f := colorjson.NewFormatter(&buf)
+		f.FileLinks = true
+		if err := f.Encode(v, colorjson.Deterministic()); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = buf.String()
+		} else if buf.String() != first {
+			t.Fatalf("got %q, then %q", first, buf.String())
+		}
+	}
+	if want := `{ "a": [ 1 ], "b": { "X": 0, "Y": 0 }, "c": 0.1, "d": "./go.mod" }`; first != want {
+		t.Errorf("got %q, want %q", first, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.Verify = true
+	f.Indent = 2
+
+	v := map[string]interface{}{"a": []interface{}{1.5, "x\n", nil, true}, "b": map[string]int{"c": 1e6}}
+	if err := f.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 {
+		t.Error("verified output was not written")
+	}
+
+	buf.Reset()
+	f.NonStrict = true
+	f.TrailingCommas = true
+	err := f.Encode(v)
+	var rt *colorjson.RoundTripError
+	if !errors.As(err, &rt) {
+		t.Fatalf("got %v, want a RoundTripError for trailing commas", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q despite the error", buf.String())
+	}
+
+	f.NonStrict = false
+	f.TrailingCommas = false
+	if err := f.Encode(struct {
+		A []int
+		B []byte
+		C float32
+	}{C: 0.1}); err != nil {
+		t.Errorf("nil slice, bytes and float32: %v", err)
+	}
+	if err := f.Encode(struct {
+		A string `json:"a,omitempty"`
+		B int    `json:"b"`
+	}{}); err != nil {
+		t.Errorf("omitempty: %v", err)
+	}
+
+	f.KeyTransform = strings.ToLower
+	f.ExcludeKeys = []string{"secret"}
+	if err := f.Encode(map[string]interface{}{"secret": 1, "v": struct{ Name string }{"x"}}); err != nil {
+		t.Errorf("filtered and transformed: %v", err)
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(`{"a": [1, 2.5e10, "x\u0000y"], "b": {"c": null, "d": true}}`)
+	f.Add(`["\u001b[31m", -0, 1e-7, {}]`)
+	f.Add(`"😀"`)
+	f.Add(`{"a\"b\n": 1, "<k>": {"\u2028&": [2]}}`)
+
+	f.Fuzz(func(t *testing.T, doc string) {
+		var v interface{}
+		if json.Unmarshal([]byte(doc), &v) != nil {
+			return
+		}
+		if _, ok := v.(string); ok {
+			// Encode writes top-level strings as they are.
+			v = []interface{}{v}
+		}
+
+		for _, indent := range []int{0, 2} {
+			fm := colorjson.NewFormatter(ioutil.Discard)
+			fm.Verify = true
+			fm.Indent = indent
+			if err := fm.Encode(v); err != nil {
+				t.Errorf("indent %d: %v", indent, err)
+			}
+		}
+	})
+}
+
+func TestColoredElements(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.KeyColor = color.FgBlue
+	f.ColoredElements = colorjson.ElementKeys
+	if err := f.Encode(map[string]interface{}{"a": []interface{}{1, "x", true, nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{ " + color.FgBlue.Sprint(`"a": `) + `[ 1, "x", true, null ] }`; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	f.ColoredElements = colorjson.ElementValues
+	if err := f.Encode(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{ "a": ` + f.NumberColor.Sprint("1") + " }"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestReducedThemes(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.SetTheme(colorjson.Themes["minimal"])
+	if err := f.Encode(map[string]interface{}{"a": []interface{}{1, "x", nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{ " + color.FgCyan.Sprint(`"a": `) + `[ 1, "x", null ] }`; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	for _, name := range []string{"grayscale", "grayscale-light"} {
+		b := colorjson.BackgroundDark
+		if strings.HasSuffix(name, "-light") {
+			b = colorjson.BackgroundLight
+		}
+		if _, warnings := colorjson.Themes[name].Validate(b); len(warnings) != 0 {
+			t.Errorf("%s has contrast warnings %v", name, warnings)
+		}
+	}
+}
+
+func TestMonochrome(t *testing.T) {
+	var buf bytes.Buffer
+	f := colorjson.NewFormatter(&buf)
+	f.HighlightKeys = []string{"b"}
+	f.SortKeys = true
+	if err := f.Encode(map[string]interface{}{"a": true, "b": nil}, colorjson.Monochrome()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every SGR sequence only sets attributes: 1 bold, 2 faint, 4 underline.
+	for _, seq := range regexp.MustCompile(`\x1b\[([0-9;]*)m`).FindAllStringSubmatch(buf.String(), -1) {
+		for _, p := range strings.Split(seq[1], ";") {
+			if p != "0" && p != "1" && p != "2" && p != "4" {
+				t.Errorf("got color parameter %q in %q", p, buf.String())
+			}
+		}
+	}
+	if colorjson.StripANSI(buf.String()) != `{ "a": true, "b": null }` {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestBasicThemes(t *testing.T) {
+	var isBasic func(c color.PrinterFace) bool
+	isBasic = func(c color.PrinterFace) bool {
+		switch c := c.(type) {
+		case color.Color, color.Style:
+			return true
+		case colorjson.Style:
+			return (c.Color == nil || isBasic(c.Color)) && (c.Background == nil || isBasic(c.Background))
+		}
+		return false
+	}
+
+	for name := range colorjson.Themes {
+		basic, ok := colorjson.BasicThemes[name]
+		if !ok {
+			t.Errorf("theme %s has no basic variant", name)
+			continue
+		}
+		for _, c := range []color.PrinterFace{basic.Back, basic.Key, basic.String, basic.Bool, basic.Number, basic.Null, basic.Error} {
+			if !isBasic(c) {
+				t.Errorf("basic variant of %s has color %#v", name, c)
+			}
+		}
+	}
+
+	level := color.TermColorLevel()
+	color.ForceSetColorLevel(terminfo.ColorLevelBasic)
+	defer color.ForceSetColorLevel(level)
+
+	if got, _ := colorjson.ThemeFor("monokai", colorjson.BackgroundUnknown); !reflect.DeepEqual(got, colorjson.BasicThemes["monokai"]) {
+		t.Errorf("got %v for monokai on a basic terminal", got)
+	}
+	if f := colorjson.NewFormatter(nil); f.KeyColor != color.FgLightWhite {
+		t.Errorf("got default key color %#v on a basic terminal", f.KeyColor)
+	}
+}
+
+func TestIcons(t *testing.T) {
+	got := plain(t, map[string]interface{}{"a": []interface{}{1, "x", true, nil, map[string]int{}}}, func(f *colorjson.Formatter) {
+		f.Icons = &colorjson.ASCIIIcons
+	})
+	if want := `% { @ "a": * [ # 1, $ "x", ? true, ~ null, % {} ] }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	icons := colorjson.NerdFontIcons
+	icons.Key = ""
+	got = plain(t, map[string]int{"a": 1}, func(f *colorjson.Formatter) { f.Icons = &icons })
+	if want := icons.Object + ` { "a": ` + icons.Number + " 1 }"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEmojiMarkers(t *testing.T) {
+	v := []interface{}{true, false, nil, errors.New("boom")}
+	got := plain(t, v, func(f *colorjson.Formatter) { f.EmojiMarkers = true })
+	if want := `[ true ✅, false ❌, null ∅, "boom" ⚠️ ]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.EmojiMarkers = true
+		f.MaxWidth = 80
+	})
+	if want := `[ true, false, null, "boom" ]`; got != want {
+		t.Errorf("got %s with MaxWidth, want %s", got, want)
+	}
+}
+
+func TestArrayIndexes(t *testing.T) {
+	got := plain(t, []string{"a", "b"}, func(f *colorjson.Formatter) { f.ArrayIndexes = colorjson.IndexComment })
+	if want := `[ /* 0 */ "a", /* 1 */ "b" ]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	v := make([]int, 11)
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.ArrayIndexes = colorjson.IndexGutter
+		f.Indent = 2
+	})
+	lines := strings.Split(got, "\n")
+	if len(lines) != 13 || lines[1] != "   0│ 0," || lines[11] != "  10│ 0" {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+func TestKeyCounts(t *testing.T) {
+	got := plain(t, map[string]interface{}{"a": map[string]int{"b": 1}, "c": map[string]int{}}, func(f *colorjson.Formatter) {
+		f.KeyCounts = true
+		f.SortKeys = true
+	})
+	if want := `{ /* 2 keys */ "a": { /* 1 key */ "b": 1 }, "c": {} }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSizeDepth(t *testing.T) {
+	v := map[string]interface{}{"a": map[string]interface{}{"b": []int{1, 2}}, "c": 1}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.SizeDepth = 1
+		f.SortKeys = true
+	})
+	if want := `{ "a": /* 11 B */ { "b": [ 1, 2 ] }, "c": 1 }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got = plain(t, v, func(f *colorjson.Formatter) {
+		f.SizeDepth = 2
+		f.SortKeys = true
+	})
+	if want := `{ "a": /* 11 B */ { "b": /* 5 B */ [ 1, 2 ] }, "c": 1 }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestDepthGutter(t *testing.T) {
+	v := map[string]interface{}{"a": map[string]interface{}{"b": []int{1}}}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.DepthGutter = colorjson.GutterNumber
+	})
+	want := "0│ {\n1│   \"a\": {\n2│     \"b\": [\n3│       1\n2│     ]\n1│   }\n0│ }"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, []interface{}{1, []int{2, 3}}, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.NonStrict = true
+		f.CommaFirst = true
+		f.DepthGutter = colorjson.GutterNumber
+	})
+	if want := "0│ [\n1│   1\n1│ , [\n2│     2\n2│   , 3\n1│   ]\n0│ ]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, []int{1}, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.DepthGutter = colorjson.GutterBlock
+		f.TerminateWithNewline = true
+	})
+	if want := "▌ [\n▌   1\n▌ ]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStreamHeader(t *testing.T) {
+	in := `{"time":"12:03:01","service":"api","http":{"status":500}}
+[1]
+`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.DisabledColor = true
+	s.Header = "{time} service={service} status={http.status}{missing}"
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := "── 12:03:01 service=api status=500 ──\n" +
+		`{ "time": "12:03:01", "service": "api", "http": { "status": 500 } }` + "\n" +
+		"──  service= status= ──\n[ 1 ]\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStreamSeparator(t *testing.T) {
+	for _, tc := range []struct {
+		sep  colorjson.Separator
+		want string
+	}{
+		{colorjson.SeparatorNone, "1\n2\n"},
+		{colorjson.SeparatorBlank, "1\n\n2\n"},
+		{colorjson.SeparatorRule, "1\n" + strings.Repeat("─", 40) + "\n2\n"},
+		{colorjson.SeparatorCounter, "── #1 ──\n1\n── #2 ──\n2\n"},
+		{colorjson.SeparatorRS, "\x1e1\n\x1e2\n"},
+	} {
+		var buf bytes.Buffer
+		s := colorjson.NewStream(&buf)
+		s.DisabledColor = true
+		s.Separator = tc.sep
+		if err := s.Copy(strings.NewReader("\x1e1\n\x1e2\n")); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tc.want {
+			t.Errorf("separator %d: got %q, want %q", tc.sep, buf.String(), tc.want)
+		}
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	in := ": keep-alive\r\n" +
+		"event: update\r\nid: 7\r\ndata: {\"a\":\r\ndata: 1}\r\n\r\n" +
+		"retry: 1000\n\n" +
+		"data: [DONE]\n"
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.DisabledColor = true
+	if err := s.CopySSE(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "event: update id: 7\n{ \"a\": 1 }\n[DONE]\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+// writeCounter counts the writes it gets.
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestAtomicWrites(t *testing.T) {
+	v := map[string]interface{}{"items": make([]int, 5000)}
+	for _, stream := range []bool{false, true} {
+		w := &writeCounter{}
+		f := colorjson.NewFormatter(w)
+		f.Indent = 2
+		f.AutoFlushEvery = 100
+		f.AtomicWrites = true
+		f.TerminateWithNewline = true
+		var err error
+		if stream {
+			s := colorjson.NewStream(w)
+			s.Formatter = f
+			err = s.Copy(strings.NewReader(`{"items":[` + strings.Repeat("0,", 4999) + `0]}`))
+		} else {
+			err = f.Encode(v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Len() < 10000 || w.writes != 1 {
+			t.Errorf("stream %v: wrote %d bytes in %d writes, want one", stream, w.Len(), w.writes)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor waits until b ends with want.
+func waitFor(t *testing.T, b *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasSuffix(b.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, waiting for %q", b.String(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(`{"old":true}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out syncBuffer
+	f := colorjson.NewFormatter(nil)
+	f.DisabledColor = true
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- colorjson.Follow(ctx, path, &out, f) }()
+	time.Sleep(50 * time.Millisecond)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"a":`)
+	time.Sleep(150 * time.Millisecond)
+	file.WriteString("1}\nnot json\n")
+	waitFor(t, &out, "{ \"a\": 1 }\nnot json\n")
+
+	// Truncation.
+	file.Truncate(0)
+	file.Seek(0, io.SeekStart)
+	file.WriteString(`{"b":2}` + "\n")
+	file.Close()
+	waitFor(t, &out, "{ \"b\": 2 }\n")
+
+	// Rotation.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"c":3}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, &out, "{ \"c\": 3 }\n")
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if strings.Contains(out.String(), "old") {
+		t.Errorf("wrote a record that was there before: %q", out.String())
+	}
+}
+
+func TestStreamCopyMixed(t *testing.T) {
+	in := "2024-05-01 INFO payload={\"a\":[1,true]} done\n" +
+		"[INFO] list=[1, {\"b\":null}] {not json}\n" +
+		"no newline {\"c\":\"}\"}"
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.Indent = 2
+	s.ColoredElements = colorjson.ElementNumbers
+	if err := s.CopyMixed(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-05-01 INFO payload={ \"a\": [ " + color.FgCyan.Sprint("1") + ", true ] } done\n" +
+		"[INFO] list=[ " + color.FgCyan.Sprint("1") + ", { \"b\": null } ] {not json}\n" +
+		"no newline { \"c\": \"}\" }"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestKeyOrder(t *testing.T) {
+	v := map[string]interface{}{"b": 1, "a": 2, "id": 3, "kind": 4}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.SortKeys = true
+		f.KeyOrder = []string{"kind", "id"}
+	})
+	if want := `{ "kind": 4, "id": 3, "a": 2, "b": 1 }`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestJournaldPreset(t *testing.T) {
+	in := `{"_PID":"42","MESSAGE":"disk full","__REALTIME_TIMESTAMP":"1714564981000000","PRIORITY":"3","_SYSTEMD_UNIT":"app.service"}`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	colorjson.Presets["journald"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), color.FgRed.Sprint("┃ ")) {
+		t.Errorf("error priority not marked red: %q", buf.String())
+	}
+	want := `┃ { "__REALTIME_TIMESTAMP": "1714564981000000", "PRIORITY": "3", "_SYSTEMD_UNIT": "app.service", "_PID": "42", "MESSAGE": "disk full" }` + "\n"
+	if got := colorjson.StripANSI(buf.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestKubernetesPreset(t *testing.T) {
+	in := `{"status":{"conditions":[{"type":"Ready","status":"True"},{"type":"Synced","status":"False"}]},
+"spec":{"replicas":1},
+"metadata":{"managedFields":[{"manager":"kubectl"}],"namespace":"default","name":"web"},
+"apiVersion":"apps/v1","kind":"Deployment"}`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.ColoredElements = colorjson.ElementStrings
+	s.StringColor = color.FgWhite
+	colorjson.Presets["k8s"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{ "kind": "Deployment", "apiVersion": "apps/v1", "metadata": { "name": "web", "namespace": "default", "managedFields": …filtered }, "spec": { "replicas": 1 }, "status": { "conditions": [ { "type": "Ready", "status": "True" }, { "type": "Synced", "status": "False" } ] } }` + "\n"
+	if got := colorjson.StripANSI(buf.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.Contains(buf.String(), color.FgGreen.Sprint(`"True"`)) || !strings.Contains(buf.String(), color.FgRed.Sprint(`"False"`)) {
+		t.Errorf("condition statuses not colored: %q", buf.String())
+	}
+}
+
+func TestCloudTrailPreset(t *testing.T) {
+	in := `{"sourceIPAddress":"203.0.113.7","eventName":"GetObject","eventTime":"2024-05-01T12:03:01Z","userIdentity":{"arn":"arn:aws:iam::1:user/ann"}}
+{"eventName":"PutObject","errorCode":"AccessDenied"}
+{"eventName":"RunInstances","errorCode":"Client.InsufficientInstanceCapacity"}
+`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	colorjson.Presets["cloudtrail"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %q", buf.String())
+	}
+	if want := `{ "eventTime": "2024-05-01T12:03:01Z", "eventName": "GetObject", "userIdentity": { "arn": "arn:aws:iam::1:user/ann" }, "sourceIPAddress": "203.0.113.7" }`; colorjson.StripANSI(lines[0]) != want {
+		t.Errorf("got %q, want %q", colorjson.StripANSI(lines[0]), want)
+	}
+	if strings.Contains(lines[0], "┃") {
+		t.Errorf("successful event was tinted: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], color.FgMagenta.Sprint("┃ ")) {
+		t.Errorf("denied event not marked magenta: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], color.FgRed.Sprint("┃ ")) {
+		t.Errorf("failed event not marked red: %q", lines[2])
+	}
+}
+
+func TestTerraformPreset(t *testing.T) {
+	in := `{"format_version":"1.2","resource_changes":[
+{"address":"aws_s3_bucket.logs","change":{"actions":["update"],"before":{"bucket":"logs","tags":{"env":"dev"}},"after":{"bucket":"logs","tags":{"env":"prod"}}}},
+{"address":"aws_instance.web","change":{"actions":["delete","create"],"before":{"ami":"a"},"after":{"ami":"b"}}}]}`
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.ColoredElements = colorjson.ElementStrings
+	s.StringColor = color.FgWhite
+	s.ValueColor = func(path, value string) color.PrinterFace {
+		if value == "1.2" {
+			return color.FgCyan
+		}
+		return nil
+	}
+	colorjson.Presets["terraform"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{ "format_version": "1.2", "resource_changes": [ ` +
+		`{ "address": "aws_s3_bucket.logs", "change": { "actions": [ "update" ], "before": { "bucket": …filtered, "tags": { "env": "dev" } }, "after": { "bucket": …filtered, "tags": { "env": "prod" } } } }, ` +
+		`{ "address": "aws_instance.web", "change": { "actions": [ "delete", "create" ], "before": { "ami": "a" }, "after": { "ami": "b" } } } ] }` + "\n"
+	if got := colorjson.StripANSI(buf.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, colored := range []string{
+		color.FgYellow.Sprint(`"aws_s3_bucket.logs"`),
+		color.FgYellow.Sprint(`"update"`),
+		color.FgMagenta.Sprint(`"aws_instance.web"`),
+		color.FgWhite.Sprint(`"a"`),
+		color.FgCyan.Sprint(`"1.2"`),
+	} {
+		if !strings.Contains(buf.String(), colored) {
+			t.Errorf("missing %q in %q", colored, buf.String())
+		}
+	}
+}
+
+func TestOpenAPIPreset(t *testing.T) {
+	in := `{"paths":{"/pets":{"get":{"responses":{"200":{"$ref":"#/components/responses/Pets"},"4XX":{"description":"bad"}}}}},
+"components":{"responses":{"Pets":{"description":"pets","schema":{"$ref":"#/components/schemas/Pet"}}},
+"schemas":{"Pet":{"properties":{"parent":{"$ref":"#/components/schemas/Pet"}}}}},"openapi":"3.0.0"}`
+
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	colorjson.Presets["openapi"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	link := "\x1b]8;;#/components/responses/Pets\x1b\\"
+	if !strings.Contains(buf.String(), link) {
+		t.Errorf("$ref not linked: %q", buf.String())
+	}
+	for _, colored := range []string{
+		color.FgBlue.Sprint(`"get": `),
+		color.FgGreen.Sprint(`"200": `),
+		color.FgYellow.Sprint(`"4XX": `),
+	} {
+		if !strings.Contains(buf.String(), colored) {
+			t.Errorf("missing %q in %q", colored, buf.String())
+		}
+	}
+
+	buf.Reset()
+	s = colorjson.NewStream(&buf)
+	s.DisabledColor = true
+	colorjson.Presets["openapi-resolved"](s)
+	if err := s.Copy(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	pets := `{ "$ref": "#/components/responses/Pets", "description": "pets", "schema": { "$ref": "#/components/schemas/Pet", "properties": { "parent": { "$ref": "#/components/schemas/Pet" } } } }`
+	if want := `"200": ` + pets; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %s, want it to contain %s", buf.String(), want)
+	}
+	if !strings.HasPrefix(buf.String(), `{ "openapi": "3.0.0", "paths"`) {
+		t.Errorf("got %s, want openapi first", buf.String())
+	}
+}
+
+func TestGeoJSON(t *testing.T) {
+	v := map[string]interface{}{
+		"type": "Feature",
+		"geometry": map[string]interface{}{
+			"type":        "Polygon",
+			"coordinates": [][][]float64{{{0, 0}, {1, 0}, {0, 1}, {0, 0}}},
+		},
+		"properties": map[string]interface{}{"coordinates": []int{1, 2}},
+	}
+	got := plain(t, v, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.SortKeys = true
+		f.GeoJSON = true
+	})
+	want := `{
+  "geometry": {
+    "coordinates": [
+      [ [ 0, 0 ], [ 1, 0 ], [ 0, 1 ], [ 0, 0 ] ]
+    ],
+    "type": "Polygon"
+  },
+  "properties": {
+    "coordinates": [
+      1,
+      2
+    ]
+  },
+  "type": "Feature"
+}`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	ring := make([][]float64, 150)
+	for i := range ring {
+		ring[i] = []float64{float64(i), 0}
+	}
+	got = plain(t, map[string]interface{}{"type": "LineString", "coordinates": ring}, func(f *colorjson.Formatter) {
+		f.Indent = 2
+		f.GeoJSON = true
+	})
+	if !strings.Contains(got, `"coordinates": /* 150 positions */ [ [ 0, 0 ], [ 1, 0 ],`) {
+		t.Errorf("long line not summarized: %s", got[:200])
+	}
+}
+
+func TestJWTPreview(t *testing.T) {
+	part := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	token := part(`{"alg":"HS256","typ":"JWT"}`) + "." + part(`{"sub":"42","admin":true}`) + ".c2lnbmF0dXJl"
+
+	got := plain(t, map[string]string{"token": token}, func(f *colorjson.Formatter) { f.JWTPreview = true })
+	want := `{ "token": "` + token + `" /* JWT, signature not verified: header { "alg": "HS256", "typ": "JWT" } claims { "sub": "42", "admin": true } */ }`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	token = part(`{"alg":"none"}`) + "." + part(`{"sub":"a */ b"}`) + ".c2lnbmF0dXJl"
+	got = plain(t, []string{token}, func(f *colorjson.Formatter) { f.JWTPreview = true })
+	want = `[ "` + token + `" /* JWT, signature not verified: header { "alg": "none" } claims { "sub": "a *\/ b" } */ ]`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, s := range []string{"a.b.c", part(`{"typ":"JWT"}`) + "." + part(`{}`) + ".x", token + ".extra"} {
+		got = plain(t, []string{s}, func(f *colorjson.Formatter) { f.JWTPreview = true })
+		if want := `[ "` + s + `" ]`; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestBase64Preview(t *testing.T) {
+	text := base64.StdEncoding.EncodeToString([]byte("hello, world"))
+	doc := base64.RawURLEncoding.EncodeToString([]byte(`{"id":7,"tags":["a"]}`))
+	binary := base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 0xff, 0xfe, 3})
+	long := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 40)))
+
+	got := plain(t, []string{text, doc, binary, "username", long}, func(f *colorjson.Formatter) {
+		f.Base64Preview = true
+		f.Base64PreviewMaxBytes = 32
+	})
+	want := `[ "` + text + `" /* base64: "hello, world" */, "` + doc + `" /* base64: { "id": 7, "tags": [ "a" ] } */, "` + binary + `", "username", "` + long + `" ]`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	doc = base64.StdEncoding.EncodeToString([]byte(`["a */ b"]`))
+	got = plain(t, []string{"YSAqLyBi", doc}, func(f *colorjson.Formatter) { f.Base64Preview = true })
+	want = `[ "YSAqLyBi" /* base64: "a *\/ b" */, "` + doc + `" /* base64: [ "a *\/ b" ] */ ]`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestQueryStringPreview(t *testing.T) {
+	got := plain(t, []string{
+		"grant_type=password&scope=read+write&scope=admin",
+		"https://example.com/cb?code=a%2Fb#state",
+		"abc=",
+		"a = b",
+		"no params",
+	}, func(f *colorjson.Formatter) { f.QueryStringPreview = true })
+	want := `[ "grant_type=password\u0026scope=read+write\u0026scope=admin" /* query: { "grant_type": "password", "scope": [ "read write", "admin" ] } */, ` +
+		`"https://example.com/cb?code=a%2Fb#state" /* query: { "code": "a/b" } */, "abc=", "a = b", "no params" ]`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = plain(t, []string{"q=a+*/+b"}, func(f *colorjson.Formatter) { f.QueryStringPreview = true })
+	if want := `[ "q=a+*/+b" /* query: { "q": "a *\/ b" } */ ]`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	type user struct {
+		Name  string            `json:"name"`
+		Age   int               `json:"age"`
+		Admin *bool             `json:"admin"`
+		Tags  []string          `json:"tags"`
+		Meta  map[string]string `json:"meta"`
+	}
+	yes := true
+	users := []user{
+		{Name: "Ann, Jr.", Age: 41, Admin: &yes, Tags: []string{"a"}, Meta: map[string]string{"team": "core"}},
+		{Name: "Bob", Age: 7},
+	}
+
+	var buf bytes.Buffer
+	if err := colorjson.ExportCSV(&buf, users); err != nil {
+		t.Fatal(err)
+	}
+	want := "name,age,admin,tags,meta\n" +
+		"\"Ann, Jr.\",41,true,\"[\"\"a\"\"]\",\"{\"\"team\"\":\"\"core\"\"}\"\n" +
+		"Bob,7,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	f := colorjson.NewFormatter(nil)
+	f.ExcludeKeys = []string{"age"}
+	if err := colorjson.ExportCSV(&buf, users, colorjson.CSVFlatten("."), colorjson.CSVFilter(f)); err != nil {
+		t.Fatal(err)
+	}
+	want = "name,admin,tags,meta.team\n\"Ann, Jr.\",true,\"[\"\"a\"\"]\",core\nBob,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := colorjson.ExportCSV(&buf, users, colorjson.CSVColumns("age", "name")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "age,name\n41,\"Ann, Jr.\"\n7,Bob\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := colorjson.ExportCSV(&buf, []int{1}); err == nil {
+		t.Error("expected an error for rows that aren't objects")
+	}
+}
+
+func TestStreamCopyCSV(t *testing.T) {
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	q, err := colorjson.ParseQuery(".items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Query = q
+	in := `{"items":[{"id":1,"user":{"name":"a"}}]}` + "\n" + `{"items":[{"id":2,"extra":true}]}`
+	if err := s.CopyCSV(strings.NewReader(in), colorjson.CSVFlatten("_")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id,user_name,extra\n1,a,\n2,,true\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExportTSV(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"cmd": "=SUM(A1)", "note": "tab\there", "n": -3},
+		{"cmd": "ls", "note": "line\nbreak \"quoted\"", "n": 4},
+	}
+
+	var buf bytes.Buffer
+	if err := colorjson.ExportTSV(&buf, rows, colorjson.CSVEscapeFormulas()); err != nil {
+		t.Fatal(err)
+	}
+	want := "cmd\tn\tnote\n'=SUM(A1)\t-3\t\"tab\there\"\nls\t4\t\"line\nbreak \"\"quoted\"\"\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	rows = []map[string]interface{}{{`=HYPERLINK("http://x")`: "\t=1", "\r": "\r@x"}}
+	if err := colorjson.ExportCSV(&buf, rows, colorjson.CSVEscapeFormulas()); err != nil {
+		t.Fatal(err)
+	}
+	want = "\"'\r\",\"'=HYPERLINK(\"\"http://x\"\")\"\n\"'\r@x\",'\t=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	f := colorjson.NewFormatter(nil)
+	if err := colorjson.ExportTSV(&buf, []map[string]interface{}{{"n": 1}}, colorjson.CSVColors(f)); err != nil {
+		t.Fatal(err)
+	}
+	want = color.C256(250).Sprint("n") + "\n" + color.FgCyan.Sprint("1") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStreamCopyJSONRPC(t *testing.T) {
+	frame := func(s string) string { return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(s), s) }
+	in := frame(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`) +
+		frame(`{"jsonrpc":"2.0","method":"initialized"}`) +
+		frame(`{"jsonrpc":"2.0","id":1,"result":{"ok":true}}`) +
+		frame(`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"nope"}}`)
+
+	var buf bytes.Buffer
+	s := colorjson.NewStream(&buf)
+	s.DisabledColor = true
+	if err := s.CopyJSONRPC(strings.NewReader(in), colorjson.RPCInferred); err != nil {
+		t.Fatal(err)
+	}
+	want := "→ initialize #1\n{ \"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"initialize\", \"params\": {} }\n" +
+		"→ initialized (notification)\n{ \"jsonrpc\": \"2.0\", \"method\": \"initialized\" }\n" +
+		"← initialize #1\n{ \"jsonrpc\": \"2.0\", \"id\": 1, \"result\": { \"ok\": true } }\n" +
+		"← #2 error -32601\n{ \"jsonrpc\": \"2.0\", \"id\": 2, \"error\": { \"code\": -32601, \"message\": \"nope\" } }\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	in = `[{"jsonrpc":"2.0","id":"a","method":"sum"}]` + "\n" + `{"jsonrpc":"2.0","id":"a","result":3}`
+	if err := s.CopyJSONRPC(strings.NewReader(in), colorjson.RPCInferred); err != nil {
+		t.Fatal(err)
+	}
+	want = "→ sum #\"a\"\n{ \"jsonrpc\": \"2.0\", \"id\": \"a\", \"method\": \"sum\" }\n" +
+		"← sum #\"a\"\n{ \"jsonrpc\": \"2.0\", \"id\": \"a\", \"result\": 3 }\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	v := []interface{}{float32(0.1), 1e300, 1e-7, 2.5, float32(1e21), 0.0}
+	got := plain(t, v, nil)
+	if want := `[ 0.1, 1e+300, 1e-7, 2.5, 1e+21, 0 ]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+// Package colorjsontest helps test code whose output embeds colorjson
+// output, comparing it without the escape sequences so tests don't break
+// on color changes.
+package colorjsontest
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/olebeck/colorjson"
+)
+
+// UpdateEnv set to a true value such as "1" makes AssertGolden rewrite the
+// golden files with the output it is given instead of comparing them.
+const UpdateEnv = "COLORJSON_UPDATE_GOLDEN"
+
+// AssertEqualIgnoringColor fails the test if want and got differ once
+// escape sequences are removed from both.
+func AssertEqualIgnoringColor(t testing.TB, want, got string) {
+	t.Helper()
+	want, got = colorjson.StripANSI(want), colorjson.StripANSI(got)
+	if want != got {
+		t.Errorf("output differs ignoring color%s", diff(want, got))
+	}
+}
+
+// AssertGolden fails the test if got, without escape sequences, differs
+// from the contents of the golden file at path. With UpdateEnv set the file
+// is written instead.
+func AssertGolden(t testing.TB, path, got string) {
+	t.Helper()
+	got = colorjson.StripANSI(got)
+
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateEnv)); update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (set %s=1 to create it)", err, UpdateEnv)
+	}
+	if string(want) != got {
+		t.Errorf("output differs from %s%s", path, diff(string(want), got))
+	}
+}
+
+// diff describes the first line where want and got differ.
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return "\nline " + strconv.Itoa(i+1) + ":\n  want: " + strconv.Quote(w) + "\n  got:  " + strconv.Quote(g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package colorjsontest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertEqualIgnoringColor(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertEqualIgnoringColor(r, "{ \"a\": 1 }", "\x1b[97m{ \x1b[0m\x1b[38;5;250m\"a\"\x1b[0m: \x1b[36m1\x1b[0m }")
+	if len(r.failures) != 0 {
+		t.Errorf("got failures %q", r.failures)
+	}
+
+	AssertEqualIgnoringColor(r, "{\n  \"a\": 1\n}", "{\n  \"a\": \x1b[36m2\x1b[0m\n}")
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "line 2:") {
+		t.Errorf("got failures %q, want one for line 2", r.failures)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "out.golden")
+
+	t.Setenv(UpdateEnv, "1")
+	AssertGolden(t, path, "\x1b[36m1\x1b[0m\n")
+	if data, _ := os.ReadFile(path); string(data) != "1\n" {
+		t.Errorf("wrote %q", data)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	r := &recorder{TB: t}
+	AssertGolden(r, path, "\x1b[32m1\x1b[0m\n")
+	AssertGolden(r, path, "2\n")
+	if len(r.failures) != 1 {
+		t.Errorf("got failures %q, want one", r.failures)
+	}
+}
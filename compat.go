@@ -0,0 +1,119 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// compatNode marshals n with encoding/json, honoring its tags, Marshalers
+// and escaping, and parses the result back so it is written with the same
+// text. Values read from unexported fields can't be marshaled and are left
+// alone.
+func compatNode(n node) (node, error) {
+	if !n.fast && n.value.IsValid() && !n.value.CanInterface() {
+		return n, nil
+	}
+
+	data, err := json.Marshal(n.iface())
+	if err != nil {
+		return n, err
+	}
+
+	v, err := parseRaw(data)
+	if err != nil {
+		return n, err
+	}
+	return node{plain: v, fast: true}, nil
+}
+
+// marshalRawString writes a string from encoding/json output. It is only
+// written as parsed if quoting it again would give different text.
+func (f *Formatter) marshalRawString(raw rawString, w *bufio.Writer) (int, error) {
+	var s string
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return 0, err
+	}
+
+	if quoted, _ := json.Marshal(s); f.RawStrings || string(quoted) == string(raw) {
+		return f.marshalString(s, w)
+	}
+	c := f.valueColor(ElementStrings, f.StringColor, s)
+	return w.WriteString(f.icon(stringIcon, c) + f.truncateString(string(raw), c))
+}
+
+// marshalDelegated writes a value of a kind the encoder has no case for,
+// such as a channel, or one that marshals itself, such as a time.Time, as
+// encoding/json would, returning its error for the former. Values read from
+// unexported fields can't be handed to encoding/json and are reported as
+// unsupported.
+func (f *Formatter) marshalDelegated(val reflect.Value, w *bufio.Writer, depth int) (int, error) {
+	if !val.CanInterface() {
+		return 0, &json.UnsupportedTypeError{Type: val.Type()}
+	}
+	// encoding/json calls methods with pointer receivers on addressable
+	// values, which a copy isn't.
+	if val.Kind() != reflect.Pointer && val.CanAddr() {
+		val = val.Addr()
+	}
+
+	n, err := compatNode(node{value: val})
+	if err != nil {
+		return 0, err
+	}
+	return f.marshalAny(n.plain, w, depth)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshalsItself reports whether encoding/json would have val marshal
+// itself, through a MarshalJSON or MarshalText method.
+func marshalsItself(val reflect.Value) bool {
+	t := val.Type()
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if !val.CanAddr() {
+		return false
+	}
+	p := reflect.PointerTo(t)
+	return p.Implements(marshalerType) || p.Implements(textMarshalerType)
+}
+
+// isBytes reports whether encoding/json writes values of the slice type t
+// as base64 strings.
+func isBytes(t reflect.Type) bool {
+	if t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	p := reflect.PointerTo(t.Elem())
+	return !p.Implements(marshalerType) && !p.Implements(textMarshalerType)
+}
+
+// mapKey returns the text of a map key as encoding/json writes it.
+func mapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.CanInterface() {
+		if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+			if key.Kind() == reflect.Pointer && key.IsNil() {
+				return "", nil
+			}
+			text, err := tm.MarshalText()
+			return string(text), err
+		}
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: key.Type()}
+}
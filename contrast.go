@@ -0,0 +1,148 @@
+package colorjson
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gookit/color"
+)
+
+// minContrast is the lowest contrast ratio Validate accepts, the WCAG
+// minimum for large text.
+const minContrast = 3.0
+
+// ContrastWarning reports a theme color that is hard to read on the
+// background it was validated against.
+type ContrastWarning struct {
+	// Element is the Theme field holding the color, e.g. "Key".
+	Element string
+	// Ratio is the contrast ratio of the original color, from 1 to 21.
+	Ratio float64
+}
+
+func (w ContrastWarning) String() string {
+	return fmt.Sprintf("%s color has contrast ratio %.1f:1, below %.1f:1", w.Element, w.Ratio, minContrast)
+}
+
+// Validate checks the contrast of each color of t against background b. It
+// returns t with every unreadable color lightened or darkened until it is
+// readable, and a warning for each one that was adjusted. Colors whose RGB
+// value isn't known, and every color on BackgroundUnknown, are left alone.
+func (t Theme) Validate(b Background) (Theme, []ContrastWarning) {
+	var bg [3]uint8
+	switch b {
+	case BackgroundDark:
+		bg = [3]uint8{0, 0, 0}
+	case BackgroundLight:
+		bg = [3]uint8{255, 255, 255}
+	default:
+		return t, nil
+	}
+
+	var warnings []ContrastWarning
+	elements := []struct {
+		name string
+		c    *color.PrinterFace
+	}{
+		{"Back", &t.Back},
+		{"Key", &t.Key},
+		{"String", &t.String},
+		{"Bool", &t.Bool},
+		{"Number", &t.Number},
+		{"Null", &t.Null},
+		{"Error", &t.Error},
+	}
+	for _, e := range elements {
+		fg, ok := rgbOf(*e.c)
+		if !ok {
+			continue
+		}
+		ratio := contrastRatio(fg, bg)
+		if ratio >= minContrast {
+			continue
+		}
+
+		warnings = append(warnings, ContrastWarning{Element: e.name, Ratio: ratio})
+		*e.c = withColor(*e.c, readable(fg, bg))
+	}
+	return t, warnings
+}
+
+// rgbOf returns the foreground RGB value of c, if it has one.
+func rgbOf(c color.PrinterFace) ([3]uint8, bool) {
+	var values []int
+	switch c := c.(type) {
+	case trueColor:
+		return [3]uint8{c.r, c.g, c.b}, true
+	case Style:
+		return rgbOf(c.Color)
+	case color.Color:
+		if c.IsOption() || c.IsBg() {
+			return [3]uint8{}, false
+		}
+		values = c.RGB().Values()
+	case color.Color256:
+		values = c.RGB().Values()
+	case color.RGBColor:
+		values = c.Values()
+	case color.Style:
+		for _, fg := range c {
+			if fg.IsFg() {
+				return rgbOf(fg)
+			}
+		}
+	}
+	if len(values) != 3 {
+		return [3]uint8{}, false
+	}
+	return [3]uint8{uint8(values[0]), uint8(values[1]), uint8(values[2])}, true
+}
+
+// withColor replaces the foreground of c, keeping the attributes of a Style.
+func withColor(c color.PrinterFace, fg trueColor) color.PrinterFace {
+	if s, ok := c.(Style); ok {
+		s.Color = fg
+		return s
+	}
+	return fg
+}
+
+// readable mixes fg with white on dark backgrounds, or black on light ones,
+// until it stands out from bg.
+func readable(fg, bg [3]uint8) trueColor {
+	var toward float64
+	if luminance(bg) < 0.5 {
+		toward = 255
+	}
+
+	mixed := fg
+	for step := 1; step <= 10 && contrastRatio(mixed, bg) < minContrast; step++ {
+		for i := range fg {
+			mixed[i] = uint8(math.Round(float64(fg[i]) + (toward-float64(fg[i]))*float64(step)/10))
+		}
+	}
+	return trueColor{r: mixed[0], g: mixed[1], b: mixed[2]}
+}
+
+// contrastRatio is the WCAG contrast ratio of two colors.
+func contrastRatio(a, b [3]uint8) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// luminance is the WCAG relative luminance of an sRGB color.
+func luminance(c [3]uint8) float64 {
+	var linear [3]float64
+	for i, v := range c {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			linear[i] = s / 12.92
+		} else {
+			linear[i] = math.Pow((s+0.055)/1.055, 2.4)
+		}
+	}
+	return 0.2126*linear[0] + 0.7152*linear[1] + 0.0722*linear[2]
+}
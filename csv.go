@@ -0,0 +1,337 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// CSVOption configures ExportCSV and ExportTSV.
+type CSVOption func(*csvExporter)
+
+// CSVColumns sets the columns and their order. Other fields are left out.
+// By default every field found is a column, in the order first seen.
+func CSVColumns(columns ...string) CSVOption {
+	return func(e *csvExporter) {
+		e.columns = columns
+		e.fixed = true
+	}
+}
+
+// CSVFlatten writes the fields of nested objects as columns of their own,
+// named by the keys on the way joined by separator, such as "user.name".
+// Without it, nested objects are written as JSON, like arrays always are.
+func CSVFlatten(separator string) CSVOption {
+	return func(e *csvExporter) {
+		e.separator = separator
+	}
+}
+
+// CSVComma sets the field delimiter, a comma by default.
+func CSVComma(comma rune) CSVOption {
+	return func(e *csvExporter) {
+		e.comma = comma
+	}
+}
+
+// CSVEscapeFormulas prefixes column names and text fields starting with =,
+// +, -, @, a tab or a carriage return with a single quote, so spreadsheets
+// show them instead of evaluating them.
+func CSVEscapeFormulas() CSVOption {
+	return func(e *csvExporter) {
+		e.escapeFormulas = true
+	}
+}
+
+// CSVColors colors the header and fields with f's colors, for previewing
+// in a terminal. Without it, the output has no escape sequences.
+func CSVColors(f *Formatter) CSVOption {
+	return func(e *csvExporter) {
+		e.colors = f
+	}
+}
+
+// CSVFilter leaves out the rows and fields f's IncludeKeys, ExcludeKeys,
+// FilterRules and FilterFunc hide.
+func CSVFilter(f *Formatter) CSVOption {
+	return func(e *csvExporter) {
+		e.filter = f.clone(nil)
+	}
+}
+
+type csvExporter struct {
+	columns        []string
+	fixed          bool
+	separator      string
+	comma          rune
+	escapeFormulas bool
+	colors         *Formatter
+	filter         *Formatter
+
+	seen map[string]bool
+	// set holds the columns with a value other than null in some row.
+	set map[string]bool
+}
+
+func newCSVExporter(opts []CSVOption) *csvExporter {
+	e := &csvExporter{comma: ',', seen: map[string]bool{}, set: map[string]bool{}}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ExportCSV writes v, an array of objects as encoding/json marshals it, as
+// CSV with a header row, one row per object. Scalars are written as text,
+// null as an empty field.
+func ExportCSV(w io.Writer, v interface{}, opts ...CSVOption) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+
+	rows, ok := doc.([]interface{})
+	if !ok {
+		rows = []interface{}{doc}
+	}
+	return newCSVExporter(opts).write(w, rows)
+}
+
+// ExportTSV is ExportCSV with tabs between the fields, for pasting into
+// spreadsheets.
+func ExportTSV(w io.Writer, v interface{}, opts ...CSVOption) error {
+	return ExportCSV(w, v, append([]CSVOption{CSVComma('\t')}, opts...)...)
+}
+
+// CopyCSV reads JSON documents from r and writes them as CSV, like
+// ExportCSV with s's filters. Arrays, or what Query selects from them,
+// contribute a row per element.
+func (s *Stream) CopyCSV(r io.Reader, opts ...CSVOption) error {
+	var rows []interface{}
+	err := decodeAll(rsReader{r}, func(v interface{}) error {
+		results := []interface{}{v}
+		if s.Query != nil {
+			var err error
+			if results, err = s.Query.Run(v); err != nil {
+				return err
+			}
+		}
+		for _, result := range results {
+			if list, ok := result.([]interface{}); ok {
+				rows = append(rows, list...)
+			} else {
+				rows = append(rows, result)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e := newCSVExporter(append([]CSVOption{CSVFilter(s.Formatter)}, opts...))
+	if err := e.write(s.Buffer, rows); err != nil {
+		return err
+	}
+	return s.Buffer.Flush()
+}
+
+// write writes rows, which must be objects.
+func (e *csvExporter) write(w io.Writer, rows []interface{}) error {
+	elems := make([]element, len(rows))
+	for i, row := range rows {
+		elems[i] = element{index: i, node: node{plain: row, fast: true}}
+	}
+	if e.filter != nil && e.filter.FilterFunc != nil {
+		elems = e.filter.filterElems(len(rows), func(i int) node { return node{plain: rows[i], fast: true} })
+	}
+
+	records := make([]map[string]interface{}, 0, len(elems))
+	for _, el := range elems {
+		if el.filtered {
+			continue
+		}
+		o, ok := el.plain.(*object)
+		if !ok {
+			return fmt.Errorf("colorjson: CSV row %d is not an object", el.index)
+		}
+		cells := map[string]interface{}{}
+		if e.filter != nil {
+			e.filter.pushIndex(el.index)
+		}
+		// Members of the rows are as deep as in the array they came from.
+		e.flatten(o, "", initialDepth+2, cells)
+		if e.filter != nil {
+			e.filter.pop()
+		}
+		records = append(records, cells)
+	}
+
+	if !e.fixed && e.separator != "" {
+		e.dropNullObjects()
+	}
+
+	bw := bufio.NewWriter(w)
+	header := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = e.colorField(e.quote(e.escapeFormula(column)), e.keyColor())
+	}
+	bw.WriteString(strings.Join(header, string(e.comma)) + "\n")
+	record := make([]string, len(e.columns))
+	for _, cells := range records {
+		for i, column := range e.columns {
+			v := cells[column]
+			record[i] = e.colorField(e.quote(e.field(v)), e.valueColor(v))
+		}
+		bw.WriteString(strings.Join(record, string(e.comma)) + "\n")
+	}
+	return bw.Flush()
+}
+
+// flatten adds the fields of o, whose members are at depth, to cells,
+// naming them after prefix.
+func (e *csvExporter) flatten(o *object, prefix string, depth int, cells map[string]interface{}) {
+	members := make([]member, len(o.keys))
+	for i, key := range o.keys {
+		members[i] = member{key: key, node: node{plain: o.values[i], fast: true}}
+	}
+	g := e.filter
+	if g != nil {
+		members = g.filterMembers(members, depth)
+	}
+
+	for _, m := range members {
+		if m.filtered {
+			continue
+		}
+		if g != nil {
+			g.pushKey(m.key)
+			if m.included {
+				g.included++
+			}
+		}
+
+		name := prefix + m.key
+		if sub, ok := m.plain.(*object); ok && e.separator != "" {
+			e.flatten(sub, name+e.separator, depth+1, cells)
+		} else {
+			cells[name] = m.plain
+			if m.plain != nil {
+				e.set[name] = true
+			}
+			if !e.fixed && !e.seen[name] {
+				e.seen[name] = true
+				e.columns = append(e.columns, name)
+			}
+		}
+
+		if g != nil {
+			if m.included {
+				g.included--
+			}
+			g.pop()
+		}
+	}
+}
+
+// dropNullObjects removes the columns of fields that are null where they
+// aren't flattened objects, like a missing "user" next to "user.name".
+func (e *csvExporter) dropNullObjects() {
+	var kept []string
+	for _, column := range e.columns {
+		if !e.set[column] && e.flattened(column) {
+			continue
+		}
+		kept = append(kept, column)
+	}
+	e.columns = kept
+}
+
+// flattened reports whether there are columns for the fields of column.
+func (e *csvExporter) flattened(column string) bool {
+	for _, c := range e.columns {
+		if strings.HasPrefix(c, column+e.separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the text of a field: scalars as they are, objects and
+// arrays as JSON.
+func (e *csvExporter) field(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return e.escapeFormula(v)
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// escapeFormula returns s with a single quote in front if CSVEscapeFormulas
+// is set and a spreadsheet could take it for a formula.
+func (e *csvExporter) escapeFormula(s string) string {
+	if e.escapeFormulas && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// quote quotes field if it holds the delimiter, a quote or a line break,
+// or starts with a space, doubling the quotes in it.
+func (e *csvExporter) quote(field string) string {
+	if !strings.ContainsAny(field, string(e.comma)+"\"\r\n") && !strings.HasPrefix(field, " ") && !strings.HasPrefix(field, "\t") {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+func (e *csvExporter) keyColor() color.PrinterFace {
+	if e.colors == nil {
+		return nil
+	}
+	return e.colors.KeyColor
+}
+
+// valueColor returns the color of a field holding v.
+func (e *csvExporter) valueColor(v interface{}) color.PrinterFace {
+	if e.colors == nil {
+		return nil
+	}
+	switch v.(type) {
+	case nil:
+		return nil
+	case string:
+		return e.colors.StringColor
+	case json.Number:
+		return e.colors.NumberColor
+	case bool:
+		return e.colors.BoolColor
+	}
+	return e.colors.BackColor
+}
+
+func (e *csvExporter) colorField(field string, c color.PrinterFace) string {
+	if e.colors == nil || field == "" {
+		return field
+	}
+	return e.colors.sprintColor(c, field)
+}
@@ -0,0 +1,96 @@
+package colorjson
+
+// Decorations are the tokens a document is built from besides keys and
+// scalar values. Changing them turns the output into another dialect.
+type Decorations struct {
+	ObjectOpen  string
+	ObjectClose string
+	ArrayOpen   string
+	ArrayClose  string
+	EmptyObject string
+	EmptyArray  string
+	// KeyOpen and KeyClose go around keys, unless UnquotedKeys leaves
+	// them out.
+	KeyOpen           string
+	KeyClose          string
+	KeyValueSeparator string
+	ElementSeparator  string
+	// Padding goes inside the brackets of containers written on one line.
+	Padding string
+	Null    string
+	True    string
+	False   string
+}
+
+// JSONDecorations is the default, JSON itself.
+var JSONDecorations = Decorations{
+	ObjectOpen:        startMap,
+	ObjectClose:       endMap,
+	ArrayOpen:         startArray,
+	ArrayClose:        endArray,
+	EmptyObject:       emptyMap,
+	EmptyArray:        emptyArray,
+	KeyOpen:           `"`,
+	KeyClose:          `"`,
+	KeyValueSeparator: defaultKeyValueSep,
+	ElementSeparator:  defaultElementSep,
+	Padding:           " ",
+	Null:              null,
+	True:              "true",
+	False:             "false",
+}
+
+// PythonDecorations writes Python literals, as repr would.
+var PythonDecorations = Decorations{
+	ObjectOpen:        "{",
+	ObjectClose:       "}",
+	ArrayOpen:         "[",
+	ArrayClose:        "]",
+	EmptyObject:       "{}",
+	EmptyArray:        "[]",
+	KeyOpen:           "'",
+	KeyClose:          "'",
+	KeyValueSeparator: ": ",
+	ElementSeparator:  ", ",
+	Padding:           "",
+	Null:              "None",
+	True:              "True",
+	False:             "False",
+}
+
+// LuaDecorations writes Lua table constructors.
+var LuaDecorations = Decorations{
+	ObjectOpen:        "{",
+	ObjectClose:       "}",
+	ArrayOpen:         "{",
+	ArrayClose:        "}",
+	EmptyObject:       "{}",
+	EmptyArray:        "{}",
+	KeyOpen:           `["`,
+	KeyClose:          `"]`,
+	KeyValueSeparator: " = ",
+	ElementSeparator:  ", ",
+	Padding:           " ",
+	Null:              "nil",
+	True:              "true",
+	False:             "false",
+}
+
+// compactDecorations are JSONDecorations without spacing, as encoding/json
+// writes documents on one line, for StdlibCompat.
+var compactDecorations = func() Decorations {
+	d := JSONDecorations
+	d.KeyValueSeparator, d.ElementSeparator, d.Padding = ":", ",", ""
+	return d
+}()
+
+// decorations returns the Decorations in use.
+func (f *Formatter) decorations() *Decorations {
+	if f.Decorations == nil {
+		if f.StdlibCompat && f.Indent == 0 {
+			return &compactDecorations
+		}
+		return &JSONDecorations
+	}
+	return f.Decorations
+}
@@ -0,0 +1,52 @@
+package colorjson
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+var (
+	defaultMu        sync.RWMutex
+	defaultFormatter *Formatter
+)
+
+// SetDefault makes a copy of f the formatter Marshal uses. Passing nil
+// restores the built-in default. It is safe to call concurrently with
+// Marshal and Default.
+func SetDefault(f *Formatter) {
+	var c *Formatter
+	if f != nil {
+		c = f.clone(nil)
+	}
+
+	defaultMu.Lock()
+	defaultFormatter = c
+	defaultMu.Unlock()
+}
+
+// Default returns a copy of the formatter Marshal uses, writing to nowhere
+// until its Buffer is set. The built-in default highlights the keys in
+// COLORJSON_HIGHLIGHT.
+func Default() *Formatter {
+	defaultMu.RLock()
+	f := defaultFormatter
+	defaultMu.RUnlock()
+
+	if f == nil {
+		f = NewFormatter(nil)
+		f.HighlightKeys = HighlightFromEnv()
+		return f
+	}
+	return f.clone(nil)
+}
+
+// clone returns a copy of f writing to w, without the state of an encoding
+// in progress.
+func (f *Formatter) clone(w io.Writer) *Formatter {
+	c := *f
+	c.Buffer = bufio.NewWriter(w)
+	c.path = nil
+	c.pendingFold = ""
+	return &c
+}
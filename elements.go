@@ -0,0 +1,42 @@
+package colorjson
+
+import "github.com/gookit/color"
+
+// Elements is a set of the kinds of text in a document, used to choose
+// which of them are colored.
+type Elements int
+
+const (
+	ElementKeys Elements = 1 << iota
+	ElementStrings
+	ElementNumbers
+	ElementBools
+	ElementNulls
+	ElementPunctuation
+
+	// ElementValues are all the scalar values.
+	ElementValues = ElementStrings | ElementNumbers | ElementBools | ElementNulls
+	// ElementAll is every element, the default.
+	ElementAll = ElementKeys | ElementValues | ElementPunctuation
+)
+
+// colorOf returns c if elements of kind e are colored, and nil otherwise.
+// Highlights, errors and annotations are colored regardless.
+func (f *Formatter) colorOf(e Elements, c color.PrinterFace) color.PrinterFace {
+	if f.ColoredElements&e == 0 {
+		return nil
+	}
+	return c
+}
+
+// valueColor returns the color of a scalar value of kind e whose text is
+// value: what ValueColor picks for it, or else c, if elements of kind e are
+// colored.
+func (f *Formatter) valueColor(e Elements, c color.PrinterFace, value string) color.PrinterFace {
+	if f.ValueColor != nil {
+		if vc := f.ValueColor(f.currentPath(), value); vc != nil {
+			c = vc
+		}
+	}
+	return f.colorOf(e, c)
+}
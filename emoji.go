@@ -0,0 +1,26 @@
+package colorjson
+
+import "bufio"
+
+// Markers EmojiMarkers writes after values.
+const (
+	emojiTrue  = "✅"
+	emojiFalse = "❌"
+	emojiNull  = "∅"
+	emojiError = "⚠️"
+)
+
+// emoji returns marker preceded by a space, or "" if markers are off. They
+// are also left out when MaxWidth is set, as terminals disagree on how wide
+// emoji are.
+func (f *Formatter) emoji(marker string) string {
+	if !f.EmojiMarkers || f.MaxWidth > 0 {
+		return ""
+	}
+	return " " + marker
+}
+
+// marshalErrorString writes the message of an error.
+func (f *Formatter) marshalErrorString(s string, w *bufio.Writer) (int, error) {
+	return w.WriteString(f.formatString(s, f.ErrorColor) + f.emoji(emojiError))
+}
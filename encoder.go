@@ -0,0 +1,17 @@
+package colorjson
+
+import "io"
+
+// Encoder writes colored JSON documents to a stream, each followed by a
+// newline, like json.Encoder.
+type Encoder struct {
+	*Formatter
+}
+
+// NewEncoder returns an Encoder writing to w with the default formatter
+// settings and TerminateWithNewline set.
+func NewEncoder(w io.Writer) *Encoder {
+	f := NewFormatter(w)
+	f.TerminateWithNewline = true
+	return &Encoder{Formatter: f}
+}
@@ -0,0 +1,121 @@
+package colorjson
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/xo/terminfo"
+)
+
+// Environment variables FromEnv reads.
+const (
+	// ThemeEnv names one of Themes, or is "auto" to pick the default theme
+	// for the terminal background. Themes paired in LightVariants switch to
+	// the variant matching the background.
+	ThemeEnv = "COLORJSON_THEME"
+	// BackgroundEnv overrides the detected background: "light" or "dark".
+	BackgroundEnv = "COLORJSON_BACKGROUND"
+	// IndentEnv is the number of spaces to indent with.
+	IndentEnv = "COLORJSON_INDENT"
+	// ColorEnv is the color level: "none", "16", "256" or "truecolor".
+	ColorEnv = "COLORJSON_COLOR"
+	// SortKeysEnv sorts object keys when set to a true value such as "1".
+	SortKeysEnv = "COLORJSON_SORT_KEYS"
+)
+
+// envConfig is the configuration read from the environment. Unset or
+// invalid variables leave the formatter defaults alone.
+type envConfig struct {
+	theme      *Theme
+	indent     *int
+	noColor    bool
+	colorLevel *terminfo.ColorLevel
+	sortKeys   *bool
+}
+
+// probeTimeout bounds the wait for the terminal to report its background.
+const probeTimeout = 100 * time.Millisecond
+
+var (
+	envOnce sync.Once
+	env     envConfig
+)
+
+func readEnv() envConfig {
+	var c envConfig
+
+	var level terminfo.ColorLevel
+	switch strings.ToLower(os.Getenv(ColorEnv)) {
+	case "none", "never", "off", "0":
+		c.noColor = true
+	case "16", "basic":
+		level = terminfo.ColorLevelBasic
+		c.colorLevel = &level
+	case "256":
+		level = terminfo.ColorLevelHundreds
+		c.colorLevel = &level
+	case "truecolor", "24bit":
+		level = terminfo.ColorLevelMillions
+		c.colorLevel = &level
+	}
+
+	// The theme depends on the level.
+	if c.colorLevel != nil {
+		termLevel = *c.colorLevel
+		color.ForceSetColorLevel(*c.colorLevel)
+	}
+
+	background := DetectBackground()
+	name := strings.ToLower(os.Getenv(ThemeEnv))
+	if name == "auto" {
+		name = "default"
+		if background == BackgroundUnknown {
+			background = ProbeBackground(probeTimeout)
+		}
+	}
+	if name == "" && background == BackgroundLight {
+		name = "default"
+	}
+	if t, ok := ThemeFor(name, background); ok {
+		if paletteAdjusted() {
+			t = t.ForPalette(DetectPalette())
+		}
+		c.theme = &t
+	}
+
+	if indent, err := strconv.Atoi(os.Getenv(IndentEnv)); err == nil && indent >= 0 {
+		c.indent = &indent
+	}
+
+	if sortKeys, err := strconv.ParseBool(os.Getenv(SortKeysEnv)); err == nil {
+		c.sortKeys = &sortKeys
+	}
+	return c
+}
+
+// FromEnv returns a formatter writing to w, configured by the COLORJSON_*
+// environment variables. The environment is read once, on the first call.
+func FromEnv(w io.Writer) *Formatter {
+	envOnce.Do(func() {
+		env = readEnv()
+	})
+
+	f := NewFormatter(w)
+	if env.theme != nil {
+		f.SetTheme(*env.theme)
+	}
+	if env.indent != nil {
+		f.Indent = *env.indent
+	}
+	if env.sortKeys != nil {
+		f.SortKeys = *env.sortKeys
+	}
+	f.DisabledColor = env.noColor
+	f.HighlightKeys = HighlightFromEnv()
+	return f
+}
@@ -0,0 +1,40 @@
+package colorjson
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorText is a single message of an expanded error chain.
+type errorText string
+
+// errorOf returns the error held by val, if its type implements error.
+// Nil pointers and interfaces are left for the caller to render as null.
+func errorOf(val reflect.Value) (error, bool) {
+	if !val.IsValid() || !val.CanInterface() || !val.Type().Implements(errorType) {
+		return nil, false
+	}
+	if (val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface) && val.IsNil() {
+		return nil, false
+	}
+	e, ok := val.Interface().(error)
+	return e, ok
+}
+
+// marshalError writes the message of e, or with ExpandErrors the message of
+// every error in its errors.Unwrap chain as an array.
+func (f *Formatter) marshalError(e error, w *bufio.Writer, depth int) (int, error) {
+	if !f.ExpandErrors {
+		return f.marshalErrorString(e.Error(), w)
+	}
+
+	var chain []errorText
+	for ; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, errorText(e.Error()))
+	}
+
+	return f.marshalElems(len(chain), func(i int) node { return node{plain: chain[i], fast: true} }, w, depth)
+}
@@ -1,8 +1,10 @@
+//go:build ignore
+
 package main
 
 import (
 	"encoding/json"
-	"github.com/relvacode/colorjson"
+	"github.com/olebeck/colorjson"
 	"os"
 )
 
@@ -20,9 +22,9 @@ func main() {
 	json.Unmarshal([]byte(str), &obj)
 
 	// Make a custom formatter with indent set
-	f := colorjson.NewFormatter()
+	f := colorjson.NewFormatter(os.Stdout)
 	f.Indent = 4
 
 	// Marshall the Colorized JSON to STDOUT
-	f.Marshal(os.Stdout, obj)
+	f.Encode(obj)
 }
@@ -1,8 +1,10 @@
+//go:build ignore
+
 package main
 
 import (
 	"encoding/json"
-	"github.com/relvacode/colorjson"
+	"github.com/olebeck/colorjson"
 	"os"
 )
 
@@ -0,0 +1,19 @@
+package colorjson
+
+import (
+	"testing"
+
+	"github.com/xo/terminfo"
+)
+
+// Tests don't depend on the terminal they run in.
+func init() {
+	termLevel = terminfo.ColorLevelNone
+}
+
+// SetTermLevel makes the terminal seem to support level until t ends.
+func SetTermLevel(t testing.TB, level terminfo.ColorLevel) {
+	old := termLevel
+	termLevel = level
+	t.Cleanup(func() { termLevel = old })
+}
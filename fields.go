@@ -0,0 +1,299 @@
+package colorjson
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FieldOrder selects the order in which struct fields are written.
+type FieldOrder int
+
+const (
+	// DeclarationOrder writes fields in the order they are declared.
+	DeclarationOrder FieldOrder = iota
+	// AlphabeticalOrder sorts fields by their output name.
+	AlphabeticalOrder
+	// TagPriorityOrder writes fields tagged with `colorjson:"order=N"` first,
+	// lowest N first, followed by the rest in declaration order.
+	TagPriorityOrder
+)
+
+// field is a struct field as it appears in the output, after embedded
+// structs have been flattened into their parent.
+type field struct {
+	name       string
+	index      []int
+	tagged     bool
+	unexported bool
+	quoted     bool
+	omitEmpty  bool
+	order      int
+	hasOrder   bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedFields returns the fields of struct type t in declaration order,
+// including unexported ones.
+func cachedFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fs.([]field)
+}
+
+// typeFields walks t breadth first, promoting the fields of untagged
+// anonymous structs the same way encoding/json does: of several fields with
+// the same name the shallowest wins, and at equal depth a single tagged field
+// wins. Any other conflict drops the name entirely. Unexported fields take
+// no part in this and are kept as they are.
+func typeFields(t reflect.Type) []field {
+	type queued struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields, unexported []field
+	visited := map[reflect.Type]bool{}
+	seen := map[string]bool{}
+	next := []queued{{typ: t}}
+
+	for len(next) > 0 {
+		current := next
+		next = nil
+
+		// A type embedded more than once at the same depth is walked once for
+		// each, so its fields conflict with themselves and drop out.
+		for _, q := range current {
+			visited[q.typ] = true
+		}
+
+		var level []field
+		for _, q := range current {
+			for i := 0; i < q.typ.NumField(); i++ {
+				sf := q.typ.Field(i)
+				index := append(append([]int(nil), q.index...), i)
+				order, hasOrder := tagOrder(sf.Tag.Get("colorjson"))
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" && (sf.IsExported() || sf.Anonymous) {
+					continue
+				}
+				name := tagName(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					if !visited[ft] {
+						next = append(next, queued{typ: ft, index: index})
+					}
+					continue
+				}
+
+				if !sf.IsExported() {
+					unexported = append(unexported, field{name: sf.Name, index: index, unexported: true, order: order, hasOrder: hasOrder})
+					continue
+				}
+
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				quoted := tagOption(tag, "string") && isQuotable(sf.Type)
+				omitEmpty := tagOption(tag, "omitempty")
+				level = append(level, field{name: name, index: index, tagged: tagged, quoted: quoted, omitEmpty: omitEmpty, order: order, hasOrder: hasOrder})
+			}
+		}
+
+		fields = append(fields, dominantFields(seen, level)...)
+	}
+
+	fields = append(fields, unexported...)
+	sortByIndex(fields)
+	return fields
+}
+
+// dominantFields returns the fields of one depth level that are not hidden
+// by a shallower field or by a conflict within the level itself. seen holds
+// the names of shallower levels, including those dropped in a conflict,
+// which hide deeper fields all the same.
+func dominantFields(seen map[string]bool, level []field) []field {
+	byName := map[string][]field{}
+	var order []string
+	for _, f := range level {
+		if seen[f.name] {
+			continue
+		}
+		if _, ok := byName[f.name]; !ok {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	var out []field
+	for _, name := range order {
+		seen[name] = true
+		candidates := byName[name]
+		if len(candidates) == 1 {
+			out = append(out, candidates[0])
+			continue
+		}
+
+		var tagged []field
+		for _, f := range candidates {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		if len(tagged) == 1 {
+			out = append(out, tagged[0])
+		}
+	}
+	return out
+}
+
+// sortByIndex puts promoted fields back into declaration order.
+func sortByIndex(fields []field) {
+	for i := 1; i < len(fields); i++ {
+		for j := i; j > 0 && indexLess(fields[j].index, fields[j-1].index); j-- {
+			fields[j], fields[j-1] = fields[j-1], fields[j]
+		}
+	}
+}
+
+func indexLess(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// orderFields returns fields sorted according to order, by the output
+// name key gives them. The cached slice is never modified.
+func orderFields(fields []field, order FieldOrder, key func(field) string) []field {
+	if order == DeclarationOrder {
+		return fields
+	}
+
+	sorted := append([]field(nil), fields...)
+	switch order {
+	case AlphabeticalOrder:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return key(sorted[i]) < key(sorted[j])
+		})
+	case TagPriorityOrder:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.hasOrder != b.hasOrder {
+				return a.hasOrder
+			}
+			return a.hasOrder && a.order < b.order
+		})
+	}
+	return sorted
+}
+
+// tagOrder parses the order=N option of a colorjson struct tag.
+func tagOrder(tag string) (int, bool) {
+	for _, opt := range strings.Split(tag, ",") {
+		if strings.HasPrefix(opt, "order=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(opt, "order="))
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// tagOption reports whether a json struct tag has the given option.
+func tagOption(tag, option string) bool {
+	opts := strings.Split(tag, ",")
+	for _, opt := range opts[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuotable reports whether the ,string option applies to fields of type
+// t, as it does in encoding/json.
+func isQuotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isEmptyValue reports whether v is left out by the omitempty option, as in
+// encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isValidTag reports whether name can be used as a key, following the
+// rules of encoding/json. Fields with invalid tag names keep their own.
+func isValidTag(name string) bool {
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+func tagName(tag string) string {
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports false instead
+// of panicking when it runs into a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,81 @@
+package colorjson
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileSink is a file for archiving formatted output. It is written under a
+// temporary name and only appears at its path, complete, once closed.
+type FileSink struct {
+	path string
+	tmp  *os.File
+	buf  *bufio.Writer
+	w    io.Writer
+}
+
+// NewFileSink creates a sink writing to path. With keepColor the output is
+// kept as is and a path without extension gets ".ansi"; otherwise escape
+// sequences are stripped, leaving plain JSON, and the extension is ".json".
+func NewFileSink(path string, keepColor bool) (*FileSink, error) {
+	if filepath.Ext(path) == "" {
+		if keepColor {
+			path += ".ansi"
+		} else {
+			path += ".json"
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	s := &FileSink{path: path, tmp: tmp, buf: bufio.NewWriter(tmp)}
+	s.w = s.buf
+	if !keepColor {
+		s.w = &stripWriter{w: s.buf}
+	}
+	return s, nil
+}
+
+// Path returns the path the file appears at when the sink is closed.
+func (s *FileSink) Path() string {
+	return s.path
+}
+
+func (s *FileSink) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+// Close writes out the file and renames it to its path. If anything fails
+// the temporary file is removed and the path left untouched.
+func (s *FileSink) Close() error {
+	err := s.buf.Flush()
+	if err == nil {
+		err = s.tmp.Sync()
+	}
+	if closeErr := s.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(s.tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(s.tmp.Name())
+	}
+	return err
+}
+
+// Abort discards what was written, leaving the path untouched.
+func (s *FileSink) Abort() error {
+	s.tmp.Close()
+	return os.Remove(s.tmp.Name())
+}
@@ -0,0 +1,189 @@
+package colorjson
+
+import (
+	"path"
+	"reflect"
+)
+
+// matchAny reports whether key matches one of the glob patterns.
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterRule hides members by their path and depth.
+//
+// Without Keys, the members matching Path are hidden along with everything
+// below them:
+//
+//	FilterRule{Path: "$.metadata.managedFields"}
+//
+// With Keys, only the listed keys are shown below Path:
+//
+//	FilterRule{MinDepth: 3, Keys: []string{"name", "id"}}
+type FilterRule struct {
+	// Path is the path of the member the rule is about, e.g.
+	// "$.items[*].metadata". Segments may contain glob patterns. An empty
+	// Path matches everything.
+	Path string
+	// MinDepth limits the rule to members at least this deep. Top-level
+	// members are at depth 1.
+	MinDepth int
+	// Keys are glob patterns of the keys that stay visible.
+	Keys []string
+}
+
+// hides reports whether the rule hides a member with the given path
+// segments and depth.
+func (r FilterRule) hides(segments []string, key string, depth int) bool {
+	if depth < r.MinDepth {
+		return false
+	}
+	if len(r.Keys) == 0 {
+		return r.Path == "" || matchPath(r.Path, segments, true)
+	}
+	if r.Path != "" && !matchPath(r.Path, segments[:len(segments)-1], true) {
+		return false
+	}
+	return !matchAny(r.Keys, key)
+}
+
+// filterMembers drops the members hidden by ExcludeKeys, FilterRules and
+// IncludeKeys. A member not matching IncludeKeys is kept if something below
+// it does, so the way to every included key stays visible.
+func (f *Formatter) filterMembers(members []member, depth int) []member {
+	if len(f.ExcludeKeys) == 0 && len(f.FilterRules) == 0 && f.FilterFunc == nil && (len(f.IncludeKeys) == 0 || f.included > 0) {
+		return members
+	}
+
+	kept := members[:0]
+	for _, m := range members {
+		if matchAny(f.ExcludeKeys, m.key) || f.ruleHides(m.key, depth) {
+			f.stats.Elided++
+			continue
+		}
+		if len(f.IncludeKeys) != 0 && f.included == 0 {
+			if matchAny(f.IncludeKeys, m.key) {
+				m.included = true
+			} else if !f.containsIncluded(m.node.reflect()) {
+				f.stats.Elided++
+				continue
+			}
+		}
+		if f.FilterFunc != nil {
+			f.pushKey(m.key)
+			keep := f.FilterFunc(f.currentPath(), m.node.iface())
+			f.pop()
+			if !keep {
+				f.stats.Elided++
+				if !f.ShowFiltered {
+					continue
+				}
+				m.filtered = true
+			}
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// element is an array element that survived FilterFunc.
+type element struct {
+	index int
+	node
+}
+
+// filterElems returns the array elements FilterFunc keeps. With
+// ShowFiltered the others stay in place as markers.
+func (f *Formatter) filterElems(length int, elem func(i int) node) []element {
+	kept := make([]element, 0, length)
+	for i := 0; i < length; i++ {
+		n := elem(i)
+
+		f.pushIndex(i)
+		keep := f.FilterFunc(f.currentPath(), n.iface())
+		f.pop()
+
+		if !keep {
+			f.stats.Elided++
+			if !f.ShowFiltered {
+				continue
+			}
+			n.filtered = true
+		}
+		kept = append(kept, element{index: i, node: n})
+	}
+	return kept
+}
+
+func (f *Formatter) ruleHides(key string, depth int) bool {
+	if len(f.FilterRules) == 0 {
+		return false
+	}
+
+	f.pushKey(key)
+	defer f.pop()
+
+	for _, r := range f.FilterRules {
+		if r.hides(f.path, key, depth) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsIncluded reports whether any key below v matches IncludeKeys.
+func (f *Formatter) containsIncluded(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Pointer && v.Type() == objectType && !v.IsNil() {
+		o := v.Interface().(*object)
+		for i, key := range o.keys {
+			if matchAny(f.IncludeKeys, key) || f.containsIncluded(reflect.ValueOf(o.values[i])) {
+				return true
+			}
+		}
+		return false
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if iter.Key().Kind() == reflect.String && matchAny(f.IncludeKeys, iter.Key().String()) {
+				return true
+			}
+			if f.containsIncluded(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for _, fd := range cachedFields(v.Type()) {
+			if fd.unexported && !f.IncludeUnexported {
+				continue
+			}
+			fv, ok := fieldByIndex(v, fd.index)
+			if !ok || fd.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			if matchAny(f.IncludeKeys, f.fieldKey(fd)) || f.containsIncluded(fv) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if f.containsIncluded(v.Index(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
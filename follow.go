@@ -0,0 +1,122 @@
+package colorjson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// followPoll is how often Follow checks the file for new data.
+const followPoll = 100 * time.Millisecond
+
+// Follow tails the JSON lines file at path like tail -F, colorizing the
+// records appended to it with f, or the default formatter if f is nil,
+// until ctx is done. Records already in the file are skipped. When the file
+// is truncated it is read again from the start, and when it is replaced,
+// as by log rotation, the new file is followed.
+func Follow(ctx context.Context, path string, w io.Writer, f *Formatter) error {
+	if f == nil {
+		f = NewFormatter(w)
+	} else {
+		f = f.clone(w)
+	}
+	s := NewStream(w)
+	s.Formatter = f
+	return s.Follow(ctx, path)
+}
+
+// Follow is like the Follow function, writing records as s does.
+func (s *Stream) Follow(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+	}()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(followPoll)
+	defer ticker.Stop()
+
+	var pending []byte
+	buf := make([]byte, 32<<10)
+	for {
+		for {
+			n, err := file.Read(buf)
+			pending = append(pending, buf[:n]...)
+			offset += int64(n)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if pending, err = s.writeLines(pending); err != nil {
+			return err
+		}
+
+		// While a rotated file is being replaced, path may not exist; the
+		// next tick tries again.
+		if info, err := os.Stat(path); err == nil {
+			current, err := file.Stat()
+			switch {
+			case err != nil || !os.SameFile(info, current):
+				if next, err := os.Open(path); err == nil {
+					file.Close()
+					file, offset, pending = next, 0, nil
+					continue
+				}
+			case info.Size() < offset:
+				if offset, err = file.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				pending = nil
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeLines writes the complete lines in data and returns the rest. Lines
+// that aren't JSON are written as they are.
+func (s *Stream) writeLines(data []byte) ([]byte, error) {
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return data, nil
+		}
+		line := bytes.TrimSpace(data[:i])
+		data = data[i+1:]
+		if len(line) == 0 {
+			continue
+		}
+
+		v, ok := decodeSingle(string(line))
+		if !ok {
+			s.Buffer.Write(line)
+			if err := s.Buffer.WriteByte('\n'); err != nil {
+				return nil, err
+			}
+			if err := s.Buffer.Flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := s.writeDocument(v); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package colorjson
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Box drawing characters of the frame.
+const (
+	frameTopLeft     = "╭"
+	frameTopRight    = "╮"
+	frameBottomLeft  = "╰"
+	frameBottomRight = "╯"
+	frameHorizontal  = "─"
+	frameVertical    = "│"
+)
+
+// encodeFramed writes root inside a box, with FrameTitle in its top edge.
+func (f *Formatter) encodeFramed(root node) error {
+	var buf bytes.Buffer
+	inner := f.clone(&buf)
+	inner.Frame = false
+	inner.TerminateWithNewline = false
+	inner.lines = nil
+	err := inner.encode(root)
+	f.stats = inner.stats
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	width := visibleWidth(f.FrameTitle) + 2
+	for _, line := range lines {
+		if w := visibleWidth(line); w > width {
+			width = w
+		}
+	}
+
+	var b strings.Builder
+	top := strings.Repeat(frameHorizontal, width+2)
+	if f.FrameTitle != "" {
+		title := " " + f.sprintColor(f.FrameTitleColor, f.FrameTitle) + " "
+		top = f.sprintColor(f.FrameColor, frameHorizontal) + title + f.sprintColor(f.FrameColor, strings.Repeat(frameHorizontal, width-visibleWidth(f.FrameTitle)-1))
+	} else {
+		top = f.sprintColor(f.FrameColor, top)
+	}
+	b.WriteString(f.sprintColor(f.FrameColor, frameTopLeft) + top + f.sprintColor(f.FrameColor, frameTopRight) + "\n")
+
+	side := f.sprintColor(f.FrameColor, frameVertical)
+	for _, line := range lines {
+		b.WriteString(side + " " + line + strings.Repeat(" ", width-visibleWidth(line)) + " " + side + "\n")
+	}
+	b.WriteString(f.sprintColor(f.FrameColor, frameBottomLeft+strings.Repeat(frameHorizontal, width+2)+frameBottomRight))
+	if f.TerminateWithNewline {
+		b.WriteString("\n")
+	}
+
+	n, err := f.Buffer.WriteString(b.String())
+	f.stats.Bytes = n
+	if err != nil {
+		return err
+	}
+	return f.Flush()
+}
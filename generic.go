@@ -0,0 +1,86 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// EncodeT is like Encode but skips reflection for the shapes encoding/json
+// decodes into (map[string]any, []any and slices of primitives). Any other
+// type falls back to the reflective encoder.
+func EncodeT[T any](f *Formatter, v T, opts ...Option) error {
+	f = f.withOptions(opts)
+	if _, ok := any(v).(string); ok {
+		return f.Encode(v)
+	}
+
+	return f.encode(node{plain: v, fast: true})
+}
+
+func (f *Formatter) marshalAny(v interface{}, w *bufio.Writer, depth int) (int, error) {
+	switch v := v.(type) {
+	case nil:
+		return f.marshalNull(w)
+	case string:
+		return f.marshalString(v, w)
+	case bool:
+		return f.marshalBool(v, w)
+	case float64:
+		return f.marshalNumber(formatFloat(v, 64), w)
+	case int:
+		return f.marshalNumber(strconv.Itoa(v), w)
+	case json.Number:
+		return f.marshalNumber(string(v), w)
+	case rawString:
+		return f.marshalRawString(v, w)
+	case errorText:
+		return f.marshalErrorString(string(v), w)
+	case map[string]interface{}:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		members := make([]member, 0, len(v))
+		for key, value := range v {
+			members = append(members, member{key: key, node: node{plain: value, fast: true}})
+		}
+		return f.marshalObject(members, w, depth)
+	case *object:
+		members := make([]member, len(v.keys))
+		for i, key := range v.keys {
+			members[i] = member{key: key, node: node{plain: v.values[i], fast: true}}
+			if len(v.raw) == len(v.keys) {
+				members[i].rawKey = v.raw[i]
+			}
+		}
+		return f.marshalObject(members, w, depth)
+	case []interface{}:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		return f.marshalElems(len(v), func(i int) node { return node{plain: v[i], fast: true} }, w, depth)
+	case []string:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		return f.marshalElems(len(v), func(i int) node { return node{plain: v[i], fast: true} }, w, depth)
+	case []float64:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		return f.marshalElems(len(v), func(i int) node { return node{plain: v[i], fast: true} }, w, depth)
+	case []int:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		return f.marshalElems(len(v), func(i int) node { return node{plain: v[i], fast: true} }, w, depth)
+	case []bool:
+		if v == nil {
+			return f.marshalNull(w)
+		}
+		return f.marshalElems(len(v), func(i int) node { return node{plain: v[i], fast: true} }, w, depth)
+	}
+
+	return f.marshalValue(reflect.ValueOf(v), w, depth)
+}
@@ -0,0 +1,99 @@
+package colorjson
+
+import (
+	"bufio"
+	"reflect"
+)
+
+// geoSummaryPositions is the number of positions from which a line or ring
+// is labeled with its length.
+const geoSummaryPositions = 100
+
+// geometryTypes are the GeoJSON geometry types with coordinates.
+var geometryTypes = map[string]bool{
+	"Point":           true,
+	"MultiPoint":      true,
+	"LineString":      true,
+	"MultiLineString": true,
+	"Polygon":         true,
+	"MultiPolygon":    true,
+}
+
+// markGeometry marks the coordinates member of members if they are a
+// GeoJSON geometry.
+func (f *Formatter) markGeometry(members []member) {
+	if !f.GeoJSON {
+		return
+	}
+	coordinates := -1
+	geometry := false
+	for i, m := range members {
+		switch m.key {
+		case "coordinates":
+			coordinates = i
+		case "type":
+			v := m.node.reflect()
+			for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) && !v.IsNil() {
+				v = v.Elem()
+			}
+			geometry = v.IsValid() && v.Kind() == reflect.String && geometryTypes[v.String()]
+		}
+	}
+	if geometry && coordinates >= 0 {
+		members[coordinates].geometry = true
+	}
+}
+
+// coordinateRank returns 0 for a number, 1 for a position, 2 for a list of
+// positions such as a ring, and so on, judging by the first elements, or -1
+// if v isn't made of numbers.
+func coordinateRank(v reflect.Value) int {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return -1
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 0
+	case reflect.String:
+		if v.Type() == numberType {
+			return 0
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return -1
+		}
+		if rank := coordinateRank(v.Index(0)); rank >= 0 {
+			return rank + 1
+		}
+	}
+	return -1
+}
+
+// marshalPositions writes a position or a list of positions on one line,
+// labeling long lists with their length.
+func (f *Formatter) marshalPositions(n node, w *bufio.Writer, depth int, rank int) (int, error) {
+	var wr int
+	if v := n.reflect(); rank == 2 {
+		for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		if v.Len() >= geoSummaryPositions {
+			n, err := w.WriteString(f.sprintfColor(f.CommentColor, "/* %d positions */", v.Len()) + " ")
+			if err != nil {
+				return n, err
+			}
+			wr += n
+		}
+	}
+
+	indent := f.Indent
+	f.Indent = 0
+	n2, err := f.marshalNodeValue(n, w, depth)
+	f.Indent = indent
+	return wr + n2, err
+}
@@ -1,7 +1,11 @@
 module github.com/olebeck/colorjson
 
-go 1.14
+go 1.18
 
 require (
 	github.com/gookit/color v1.5.4
+	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778
+	golang.org/x/term v0.28.0
 )
+
+require golang.org/x/sys v0.29.0 // indirect
@@ -0,0 +1,23 @@
+module github.com/olebeck/colorjson/grpcdump
+
+go 1.25.0
+
+require (
+	github.com/gookit/color v1.5.4
+	github.com/olebeck/colorjson v0.0.0
+	google.golang.org/grpc v1.82.1
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/xo/terminfo v0.0.0-20210125001918-ca9a967f8778 // indirect
+	golang.org/x/net v0.53.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
+	golang.org/x/term v0.42.0 // indirect
+	golang.org/x/text v0.36.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478 // indirect
+)
+
+// No release of colorjson is tagged yet, so this module builds only from a
+// checkout of the repository, against the parent directory.
+replace github.com/olebeck/colorjson => ../
@@ -0,0 +1,235 @@
+// Package grpcdump provides gRPC interceptors logging request and response
+// messages as colored JSON, for developing gRPC services locally.
+package grpcdump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/olebeck/colorjson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxMessage is the default limit of the JSON bytes logged of a
+// message.
+const DefaultMaxMessage = 64 << 10
+
+// Logger writes the messages of gRPC calls through Formatter. A Logger may
+// be used by several goroutines at once.
+type Logger struct {
+	Formatter *colorjson.Formatter
+	// Methods are glob patterns of the full method names logged, such as
+	// "/helloworld.Greeter/*". With none, every method is logged.
+	Methods []string
+	// MaxMessage is the most bytes of a message, as JSON, that are logged.
+	// Only the size of longer messages is logged.
+	MaxMessage int
+
+	mu sync.Mutex
+}
+
+// New returns a Logger writing to w with the default formatter.
+func New(w io.Writer) *Logger {
+	return &Logger{
+		Formatter:  colorjson.NewFormatter(w),
+		Methods:    nil,
+		MaxMessage: DefaultMaxMessage,
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor logging unary calls to a
+// server.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !l.logs(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		l.logMessage("→", info.FullMethod, req)
+		resp, err := handler(ctx, req)
+		l.logEnd(info.FullMethod, resp, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor logging unary calls made by
+// a client.
+func (l *Logger) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !l.logs(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		start := time.Now()
+		l.logMessage("→", method, req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		l.logEnd(method, reply, err, time.Since(start))
+		return err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor logging every message of
+// streaming calls to a server.
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.logs(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, l: l, method: info.FullMethod})
+		l.logEnd(info.FullMethod, nil, err, time.Since(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor logging every message of
+// streaming calls made by a client. The end of the call is logged once the
+// client has received the last message.
+func (l *Logger) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !l.logs(method) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.logEnd(method, nil, err, time.Since(start))
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, l: l, method: method, start: start}, nil
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	l      *Logger
+	method string
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	s.l.logMessage("←", s.method, m)
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.l.logMessage("→", s.method, m)
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	l      *Logger
+	method string
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	s.l.logMessage("→", s.method, m)
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		s.l.logMessage("←", s.method, m)
+	case io.EOF:
+		s.once.Do(func() { s.l.logEnd(s.method, nil, nil, time.Since(s.start)) })
+	default:
+		s.once.Do(func() { s.l.logEnd(s.method, nil, err, time.Since(s.start)) })
+	}
+	return err
+}
+
+// logs reports whether calls to method are logged.
+func (l *Logger) logs(method string) bool {
+	if len(l.Methods) == 0 {
+		return true
+	}
+	for _, p := range l.Methods {
+		if ok, _ := path.Match(p, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// logMessage writes a message sent in the direction of arrow.
+func (l *Logger) logMessage(arrow, method string, m interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.Formatter.Buffer
+	fmt.Fprintf(w, "%s %s\n", l.sprint(l.Formatter.CommentColor, arrow), method)
+	l.writeMessage(m)
+	w.Flush()
+}
+
+// logEnd writes the status a call ended with and, if it succeeded, its
+// response.
+func (l *Logger) logEnd(method string, resp interface{}, err error, elapsed time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.Formatter.Buffer
+	st := status.Convert(err)
+	code := l.sprint(color.FgGreen, st.Code().String())
+	if st.Code() != codes.OK {
+		code = l.sprint(l.Formatter.ErrorColor, st.Code().String()+": "+st.Message())
+	}
+	fmt.Fprintf(w, "%s %s %s %s\n", l.sprint(l.Formatter.CommentColor, "←"), method, code,
+		l.sprint(l.Formatter.CommentColor, elapsed.Round(time.Millisecond).String()))
+	if resp != nil && err == nil {
+		l.writeMessage(resp)
+	}
+	w.Flush()
+}
+
+// writeMessage writes m as JSON, or a note saying why it isn't shown.
+func (l *Logger) writeMessage(m interface{}) {
+	w := l.Formatter.Buffer
+	msg, ok := m.(proto.Message)
+	if !ok {
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(%T is not a protobuf message)", m)))
+		return
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		fmt.Fprintln(w, l.sprint(l.Formatter.ErrorColor, "("+err.Error()+")"))
+		return
+	}
+	// protojson randomly adds white space, so the size is counted without.
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err == nil {
+		data = compact.Bytes()
+	}
+	if len(data) > l.MaxMessage {
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(%d bytes not shown)", len(data))))
+		return
+	}
+	s := &colorjson.Stream{Formatter: l.Formatter}
+	if err := s.Copy(bytes.NewReader(data)); err != nil {
+		fmt.Fprintln(w, l.sprint(l.Formatter.ErrorColor, "("+err.Error()+")"))
+	}
+}
+
+func (l *Logger) sprint(c color.PrinterFace, s string) string {
+	if l.Formatter.DisabledColor || c == nil {
+		return s
+	}
+	return c.Sprint(s)
+}
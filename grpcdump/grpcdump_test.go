@@ -0,0 +1,99 @@
+package grpcdump
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// elapsed matches the call duration, which varies between runs.
+var elapsed = regexp.MustCompile(` [0-9.]+[µnm]?s\n`)
+
+func dial(t *testing.T, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) healthpb.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(serverOpts...)
+	hs := health.NewServer()
+	hs.SetServingStatus("api", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.Formatter.DisabledColor = true
+	client := dial(t, []grpc.ServerOption{grpc.UnaryInterceptor(l.UnaryServerInterceptor())})
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "db"}); err == nil {
+		t.Fatal("expected an error for an unknown service")
+	}
+
+	want := "→ /grpc.health.v1.Health/Check\n" +
+		`{ "service": "api" }` + "\n" +
+		"← /grpc.health.v1.Health/Check OK\n" +
+		`{ "status": "SERVING" }` + "\n" +
+		"→ /grpc.health.v1.Health/Check\n" +
+		`{ "service": "db" }` + "\n" +
+		"← /grpc.health.v1.Health/Check NotFound: unknown service\n"
+	if got := elapsed.ReplaceAllString(buf.String(), "\n"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestClientInterceptorFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.Formatter.DisabledColor = true
+	l.Methods = []string{"/grpc.health.v1.Health/Watch"}
+	l.MaxMessage = 17
+	client := dial(t, nil,
+		grpc.WithUnaryInterceptor(l.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(l.StreamClientInterceptor()))
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("logged a filtered method: %q", buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "→ /grpc.health.v1.Health/Watch\n" +
+		`{ "service": "api" }` + "\n" +
+		"← /grpc.health.v1.Health/Watch\n" +
+		"(20 bytes not shown)\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,106 @@
+package colorjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// GutterStyle selects what the depth gutter shows at the start of each
+// line.
+type GutterStyle int
+
+const (
+	// GutterOff writes no gutter.
+	GutterOff GutterStyle = iota
+	// GutterNumber writes the nesting depth of the line: " 3│ ".
+	GutterNumber
+	// GutterBlock writes a block colored by the nesting depth of the line.
+	GutterBlock
+)
+
+const gutterBlock = "▌ "
+
+// gutterColors are cycled through by depth in GutterBlock mode.
+var gutterColors = []color.PrinterFace{
+	color.FgBlue,
+	color.FgCyan,
+	color.FgGreen,
+	color.FgYellow,
+	color.FgMagenta,
+	color.FgRed,
+}
+
+// encodeGutter writes root with each line prefixed by its nesting depth.
+// The depth is read back from the indentation of the rendered lines.
+func (f *Formatter) encodeGutter(root node) error {
+	var buf bytes.Buffer
+	inner := f.clone(&buf)
+	inner.DepthGutter = GutterOff
+	inner.TerminateWithNewline = false
+	inner.lines = nil
+	err := inner.encode(root)
+	f.stats = inner.stats
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	depths := make([]int, len(lines))
+	maxDepth := 0
+	for i, line := range lines {
+		depths[i] = f.lineDepth(line)
+		if depths[i] > maxDepth {
+			maxDepth = depths[i]
+		}
+	}
+	width := len(strconv.Itoa(maxDepth))
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(f.gutterLabel(depths[i], width))
+		b.WriteString(line)
+	}
+	if f.TerminateWithNewline {
+		b.WriteString("\n")
+	}
+
+	n, err := f.Buffer.WriteString(b.String())
+	f.stats.Bytes = n
+	if err != nil {
+		return err
+	}
+	return f.Flush()
+}
+
+// lineDepth returns the nesting depth of a rendered line, counting a
+// leading comma-first separator as part of the indentation.
+func (f *Formatter) lineDepth(line string) int {
+	if f.Indent <= 0 {
+		return 0
+	}
+	line = StripANSI(line)
+	rest := strings.TrimLeft(line, " ")
+	if sep := strings.TrimSpace(f.lineSep()); sep != "" && strings.HasPrefix(rest, sep) {
+		rest = strings.TrimLeft(rest[len(sep):], " ")
+	}
+	return (len(line) - len(rest)) / f.Indent
+}
+
+// gutterLabel returns the gutter of a line at depth, with depths padded to
+// width digits.
+func (f *Formatter) gutterLabel(depth, width int) string {
+	switch f.DepthGutter {
+	case GutterNumber:
+		return f.sprintColor(f.CommentColor, fmt.Sprintf("%*d", width, depth)+indexGutterMark)
+	case GutterBlock:
+		return f.sprintColor(gutterColors[depth%len(gutterColors)], gutterBlock)
+	}
+	return ""
+}
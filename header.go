@@ -0,0 +1,62 @@
+package colorjson
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const headerRule = "──"
+
+// recordHeader renders the Header template over record v, framed as
+// "── ... ──".
+func (s *Stream) recordHeader(v interface{}) string {
+	var b strings.Builder
+	tmpl := s.Header
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(tmpl[:start])
+		b.WriteString(headerField(v, tmpl[start+1:start+end]))
+		tmpl = tmpl[start+end+1:]
+	}
+	b.WriteString(tmpl)
+	return headerRule + " " + b.String() + " " + headerRule
+}
+
+// headerField returns the field of v named name, which may be a dotted path
+// into nested objects, as text. Missing fields are empty.
+func headerField(v interface{}, name string) string {
+	o, ok := v.(*object)
+	if !ok {
+		return ""
+	}
+	if field, ok := o.get(name); ok {
+		return headerText(field)
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if field, ok := o.get(name[:i]); ok {
+			return headerField(field, name[i+1:])
+		}
+	}
+	return ""
+}
+
+func headerText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,104 @@
+package colorjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+	"github.com/xo/terminfo"
+)
+
+// trueColor is a 24-bit color written as the closest color the active
+// color level supports.
+type trueColor struct {
+	r, g, b uint8
+}
+
+// Hex parses a "#RRGGBB" (or "#RGB") color. On terminals without 24-bit
+// color support it is written as the nearest 256 or 16 color.
+func Hex(s string) (color.PrinterFace, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("colorjson: invalid hex color %q", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("colorjson: invalid hex color %q", s)
+	}
+	return trueColor{r: uint8(v >> 16), g: uint8(v >> 8), b: uint8(v)}, nil
+}
+
+func (c trueColor) String() string {
+	switch colorLevel() {
+	case terminfo.ColorLevelMillions:
+		return color.RGB(c.r, c.g, c.b).String()
+	case terminfo.ColorLevelHundreds:
+		return color.C256(color.RgbTo256(c.r, c.g, c.b)).String()
+	}
+	return strconv.Itoa(int(color.RgbToAnsi(c.r, c.g, c.b, false)))
+}
+
+func (c trueColor) Sprint(a ...interface{}) string {
+	return color.RenderCode(c.String(), a...)
+}
+
+func (c trueColor) Sprintf(format string, a ...interface{}) string {
+	return color.RenderCode(c.String(), fmt.Sprintf(format, a...))
+}
+
+func (c trueColor) Print(a ...interface{}) {
+	fmt.Print(c.Sprint(a...))
+}
+
+func (c trueColor) Printf(format string, a ...interface{}) {
+	fmt.Print(c.Sprintf(format, a...))
+}
+
+func (c trueColor) Println(a ...interface{}) {
+	fmt.Println(c.Sprint(a...))
+}
+
+// HexTheme is a Theme with colors written as "#RRGGBB" strings, so themes
+// can be defined without the color package. Empty colors are left unset.
+type HexTheme struct {
+	Back   string
+	Key    string
+	String string
+	Bool   string
+	Number string
+	Null   string
+	Error  string
+}
+
+// Theme parses the colors of h.
+func (h HexTheme) Theme() (Theme, error) {
+	var t Theme
+	colors := []struct {
+		hex string
+		dst *color.PrinterFace
+	}{
+		{h.Back, &t.Back},
+		{h.Key, &t.Key},
+		{h.String, &t.String},
+		{h.Bool, &t.Bool},
+		{h.Number, &t.Number},
+		{h.Null, &t.Null},
+		{h.Error, &t.Error},
+	}
+	for _, c := range colors {
+		if c.hex == "" {
+			continue
+		}
+		parsed, err := Hex(c.hex)
+		if err != nil {
+			return Theme{}, err
+		}
+		*c.dst = parsed
+	}
+	return t, nil
+}
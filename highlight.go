@@ -0,0 +1,112 @@
+package colorjson
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// HighlightEnv is the environment variable HighlightFromEnv reads.
+const HighlightEnv = "COLORJSON_HIGHLIGHT"
+
+// HighlightFromEnv returns the key patterns listed, comma separated, in
+// COLORJSON_HIGHLIGHT, for example "trace_id,user.email,*.error".
+func HighlightFromEnv() []string {
+	var patterns []string
+	for _, p := range strings.Split(os.Getenv(HighlightEnv), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// isHighlighted reports whether the member being written matches one of
+// the HighlightKeys patterns. A pattern is a dotted list of key globs that
+// has to match the innermost keys of the path, so "email" matches any
+// email key and "user.email" only those directly inside a user object.
+// Array indexes are ignored.
+func (f *Formatter) isHighlighted() bool {
+	if len(f.HighlightKeys) == 0 {
+		return false
+	}
+
+	keys := f.pathKeys()
+	for _, pattern := range f.HighlightKeys {
+		if matchKeySuffix(strings.Split(pattern, "."), keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyStyle returns the style KeyStyles has for the member being written,
+// or nil. Of several matching patterns the one with the most keys wins,
+// then the one with the fewest wildcards, then the first in sort order.
+func (f *Formatter) keyStyle() color.PrinterFace {
+	if len(f.KeyStyles) == 0 {
+		return nil
+	}
+
+	keys := f.pathKeys()
+	var best string
+	var bestLen, bestWild int
+	for pattern := range f.KeyStyles {
+		parts := strings.Split(pattern, ".")
+		if !matchKeySuffix(parts, keys) {
+			continue
+		}
+
+		wild := strings.Count(pattern, "*") + strings.Count(pattern, "?") + strings.Count(pattern, "[")
+		switch {
+		case len(parts) != bestLen:
+			if len(parts) < bestLen {
+				continue
+			}
+		case wild != bestWild:
+			if wild > bestWild {
+				continue
+			}
+		case pattern > best:
+			continue
+		}
+		best, bestLen, bestWild = pattern, len(parts), wild
+	}
+	if bestLen == 0 {
+		return nil
+	}
+	return f.KeyStyles[best]
+}
+
+// pathKeys returns the keys on the current path, without array indexes.
+func (f *Formatter) pathKeys() []string {
+	var keys []string
+	for _, segment := range f.path {
+		if strings.HasPrefix(segment, ".") {
+			keys = append(keys, segment[1:])
+		}
+	}
+	return keys
+}
+
+func matchKeySuffix(patterns, keys []string) bool {
+	if len(patterns) > len(keys) {
+		return false
+	}
+	keys = keys[len(keys)-len(patterns):]
+	for i, p := range patterns {
+		if !matchKey(p, keys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKey is path.Match with * and ? matching slashes too, as keys such as
+// the paths of an OpenAPI document contain them.
+func matchKey(pattern, key string) bool {
+	ok, _ := path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(key, "/", "\x00"))
+	return ok
+}
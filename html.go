@@ -0,0 +1,238 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// HTMLOption configures ExportHTML.
+type HTMLOption func(*htmlExporter)
+
+// HTMLLineNumbers numbers the lines of the exported document.
+func HTMLLineNumbers() HTMLOption {
+	return func(e *htmlExporter) {
+		e.lineNumbers = true
+	}
+}
+
+// HTMLTitle sets the title of the exported page.
+func HTMLTitle(title string) HTMLOption {
+	return func(e *htmlExporter) {
+		e.title = title
+	}
+}
+
+type htmlExporter struct {
+	theme       Theme
+	title       string
+	lineNumbers bool
+
+	body strings.Builder
+	line int
+}
+
+// ExportHTML writes v, as encoding/json marshals it, as a self-contained
+// HTML page colored by theme. Objects and arrays are details elements the
+// reader can collapse.
+func ExportHTML(w io.Writer, v interface{}, theme Theme, opts ...HTMLOption) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+
+	e := &htmlExporter{theme: theme, title: "colorjson"}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.node(doc, 0, "", true)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<div class=\"colorjson\">\n%s</div>\n</body>\n</html>\n",
+		html.EscapeString(e.title), e.css(), e.body.String())
+	return bw.Flush()
+}
+
+// node writes v at depth after prefix, its key, and followed by a comma
+// unless it is the last element.
+func (e *htmlExporter) node(v interface{}, depth int, prefix string, last bool) {
+	suffix, comma := "", ""
+	if !last {
+		suffix, comma = e.span("punct", ","), ","
+	}
+
+	var open, close string
+	var children []interface{}
+	var keys []string
+	switch v := v.(type) {
+	case *object:
+		open, close = startMap, endMap
+		children, keys = v.values, v.keys
+	case []interface{}:
+		open, close = startArray, endArray
+		children = v
+	default:
+		e.writeLine(depth, prefix+e.scalar(v)+suffix, "")
+		return
+	}
+
+	if len(children) == 0 {
+		e.writeLine(depth, prefix+e.span("punct", open+close)+suffix, "")
+		return
+	}
+
+	e.body.WriteString("<details open><summary>")
+	e.writeLine(depth, prefix+e.span("punct", open), "…"+close+comma)
+	e.body.WriteString("</summary>\n")
+	for i, child := range children {
+		childPrefix := ""
+		if keys != nil {
+			childPrefix = e.span("key", escapeText(quoteJSON(keys[i]))) + e.span("punct", ": ")
+		}
+		e.node(child, depth+1, childPrefix, i == len(children)-1)
+	}
+	e.writeLine(depth, e.span("punct", close)+suffix, "")
+	e.body.WriteString("</details>\n")
+}
+
+// writeLine writes one line of the document. fold is shown after it while
+// the details element it opens is collapsed.
+func (e *htmlExporter) writeLine(depth int, content, fold string) {
+	e.line++
+	e.body.WriteString(`<span class="line"`)
+	if fold != "" {
+		fmt.Fprintf(&e.body, ` data-fold="%s"`, html.EscapeString(fold))
+	}
+	e.body.WriteString(">")
+	if e.lineNumbers {
+		fmt.Fprintf(&e.body, `<span class="ln">%d</span>`, e.line)
+	}
+	e.body.WriteString(strings.Repeat("  ", depth) + content + "</span>\n")
+}
+
+func (e *htmlExporter) scalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return e.span("null", null)
+	case bool:
+		return e.span("bool", fmt.Sprint(v))
+	case json.Number:
+		return e.span("number", v.String())
+	case string:
+		return e.span("string", escapeText(quoteJSON(v)))
+	}
+	return html.EscapeString(fmt.Sprint(v))
+}
+
+func (e *htmlExporter) span(class, s string) string {
+	return `<span class="` + class + `">` + s + "</span>"
+}
+
+// css returns the style sheet for the theme.
+func (e *htmlExporter) css() string {
+	background := "#1e1e1e"
+	if fg, ok := rgbOf(e.theme.Back); ok && luminance(fg) < 0.5 {
+		background = "#ffffff"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "body { margin: 0; background: %s; }\n", background)
+	b.WriteString(".colorjson { font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 13px; padding: 1em; }\n")
+	b.WriteString(".line { display: block; white-space: pre; }\n")
+	b.WriteString(".ln { display: inline-block; width: 4em; margin-right: 1em; text-align: right; opacity: 0.5; user-select: none; }\n")
+	b.WriteString("summary { display: block; cursor: pointer; list-style: none; }\n")
+	b.WriteString("summary::-webkit-details-marker { display: none; }\n")
+	b.WriteString("details:not([open]) > summary > .line::after { content: attr(data-fold); opacity: 0.6; }\n")
+
+	classes := []struct {
+		name string
+		c    color.PrinterFace
+	}{
+		{".colorjson, .punct", e.theme.Back},
+		{".key", e.theme.Key},
+		{".string", e.theme.String},
+		{".bool", e.theme.Bool},
+		{".number", e.theme.Number},
+		{".null", e.theme.Null},
+	}
+	for _, class := range classes {
+		if rule := cssOf(class.c); rule != "" {
+			fmt.Fprintf(&b, "%s { %s}\n", class.name, rule)
+		}
+	}
+	return b.String()
+}
+
+// cssOf returns the declarations rendering text like c does.
+func cssOf(c color.PrinterFace) string {
+	var b strings.Builder
+	if fg, ok := rgbOf(c); ok {
+		fmt.Fprintf(&b, "color: #%02x%02x%02x; ", fg[0], fg[1], fg[2])
+	}
+
+	switch c := c.(type) {
+	case Style:
+		bg := c.Background
+		if basic, ok := bg.(color.Color); ok && basic.IsBg() {
+			bg = basic.ToFg()
+		}
+		if rgb, ok := rgbOf(bg); ok {
+			fmt.Fprintf(&b, "background: #%02x%02x%02x; ", rgb[0], rgb[1], rgb[2])
+		}
+		writeCSSAttrs(&b, c.Bold, c.Faint, c.Italic, c.Underline, c.Strikethrough)
+	case color.Style:
+		var has [10]bool
+		for _, part := range c {
+			if part.IsOption() {
+				has[part] = true
+			}
+		}
+		writeCSSAttrs(&b, has[color.OpBold], has[color.OpFuzzy], has[color.OpItalic], has[color.OpUnderscore], has[color.OpStrikethrough])
+	}
+	return b.String()
+}
+
+func writeCSSAttrs(b *strings.Builder, bold, faint, italic, underline, strikethrough bool) {
+	if bold {
+		b.WriteString("font-weight: bold; ")
+	}
+	if faint {
+		b.WriteString("opacity: 0.6; ")
+	}
+	if italic {
+		b.WriteString("font-style: italic; ")
+	}
+	switch {
+	case underline && strikethrough:
+		b.WriteString("text-decoration: underline line-through; ")
+	case underline:
+		b.WriteString("text-decoration: underline; ")
+	case strikethrough:
+		b.WriteString("text-decoration: line-through; ")
+	}
+}
+
+// escapeText escapes the characters that are special in HTML text.
+var escapeText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+// quoteJSON quotes s as JSON without escaping HTML characters, which the
+// page escapes itself.
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
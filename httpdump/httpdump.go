@@ -0,0 +1,343 @@
+// Package httpdump logs the JSON bodies of HTTP requests and responses
+// through a colorjson.Formatter, on the server side with Middleware and on
+// the client side with Transport, as well as WebSocket messages.
+package httpdump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/olebeck/colorjson"
+)
+
+// DefaultMaxBody is the default limit of the bytes logged of a body.
+const DefaultMaxBody = 64 << 10
+
+// Logger writes requests and responses with their JSON bodies. Bodies are
+// written through Formatter, so its ExcludeKeys, FilterRules and FilterFunc
+// keep secrets out of the log. A Logger may be used by several goroutines
+// at once.
+type Logger struct {
+	Formatter *colorjson.Formatter
+	// MaxBody is the most bytes of a body or WebSocket message that are
+	// logged. Longer bodies are passed on whole but only their size is
+	// logged.
+	MaxBody int
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// New returns a Logger writing to w with the default formatter.
+func New(w io.Writer) *Logger {
+	return &Logger{
+		Formatter: colorjson.NewFormatter(w),
+		MaxBody:   DefaultMaxBody,
+	}
+}
+
+// Middleware returns a handler logging every request to next and its
+// response.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		reqBody := l.capture(r.Header, &r.Body)
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, max: l.MaxBody}
+		next.ServeHTTP(rec, r)
+
+		resp := body{data: rec.body.Bytes(), truncated: rec.truncated, size: rec.size}
+		if !isJSON(w.Header()) {
+			resp = body{size: rec.size}
+		}
+		l.log(r.Method, r.URL.RequestURI(), reqBody, rec.status, resp, time.Since(start))
+	})
+}
+
+// Transport returns a RoundTripper logging every request sent through
+// next, or http.DefaultTransport if next is nil, and its response. A
+// response is logged once its body has been read to the end or closed, so
+// streamed bodies reach the caller as they arrive.
+func (l *Logger) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		if r.Body != nil {
+			r = r.Clone(r.Context())
+		}
+		reqBody := l.capture(r.Header, &r.Body)
+
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			l.logError(r.Method, r.URL.String(), err)
+			return nil, err
+		}
+		elapsed := time.Since(start)
+		l.tee(resp.Header, &resp.Body, func(respBody body) {
+			l.log(r.Method, r.URL.String(), reqBody, resp.StatusCode, respBody, elapsed)
+		})
+		return resp, nil
+	})
+}
+
+// ReverseProxy returns a handler forwarding requests to upstream and
+// logging them with their responses, a colored man in the middle for JSON
+// APIs in development.
+func (l *Logger) ReverseProxy(upstream *url.URL) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	proxy.Transport = l.Transport(nil)
+	return proxy
+}
+
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// body is a captured body.
+type body struct {
+	data      []byte
+	truncated bool
+	// unread is set for bodies closed before their end.
+	unread bool
+	// size is the size of the body, or -1 if it is unknown.
+	size int64
+}
+
+// capture reads up to MaxBody bytes of a JSON body and puts them back in
+// front of the rest. Other bodies are left alone.
+func (l *Logger) capture(header http.Header, rc *io.ReadCloser) body {
+	if *rc == nil || *rc == http.NoBody {
+		return body{}
+	}
+	if !isJSON(header) {
+		return body{size: -1}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(*rc, int64(l.MaxBody)+1))
+	*rc = readCloser{io.MultiReader(bytes.NewReader(data), *rc), *rc}
+	if err != nil {
+		return body{size: -1}
+	}
+	if len(data) > l.MaxBody {
+		return body{truncated: true, size: -1}
+	}
+	return body{data: data, size: int64(len(data))}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// tee keeps up to MaxBody bytes of a JSON body as it is read and calls done
+// with them once it has been read to the end or closed. Other bodies are
+// left alone and done is called right away.
+func (l *Logger) tee(header http.Header, rc *io.ReadCloser, done func(body)) {
+	switch {
+	case *rc == nil || *rc == http.NoBody:
+		done(body{})
+	case !isJSON(header):
+		done(body{size: -1})
+	default:
+		*rc = &teeBody{ReadCloser: *rc, max: l.MaxBody, done: done}
+	}
+}
+
+type teeBody struct {
+	io.ReadCloser
+	max  int
+	done func(body)
+
+	mu        sync.Mutex
+	data      bytes.Buffer
+	size      int64
+	truncated bool
+	finished  bool
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+
+	t.mu.Lock()
+	if t.data.Len()+n <= t.max && !t.truncated {
+		t.data.Write(p[:n])
+	} else {
+		t.truncated = true
+	}
+	t.size += int64(n)
+	t.mu.Unlock()
+
+	if err == io.EOF {
+		t.finish(false)
+	} else if err != nil {
+		t.finish(true)
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	err := t.ReadCloser.Close()
+	t.finish(true)
+	return err
+}
+
+// finish calls done the first time it is called.
+func (t *teeBody) finish(unread bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return
+	}
+	t.finished = true
+
+	switch {
+	case t.truncated:
+		t.done(body{truncated: true, size: -1})
+	case unread:
+		t.done(body{unread: true, size: -1})
+	default:
+		t.done(body{data: t.data.Bytes(), size: t.size})
+	}
+}
+
+// isJSON reports whether the Content-Type of header is JSON, such as
+// application/json, application/problem+json or application/x-ndjson.
+func isJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "ndjson")
+}
+
+func (l *Logger) log(method, target string, reqBody body, status int, respBody body, elapsed time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.Formatter.Buffer
+	fmt.Fprintf(w, "%s %s %s\n", l.sprint(l.Formatter.CommentColor, "→"), method, target)
+	l.writeBody(reqBody)
+	fmt.Fprintf(w, "%s %s %s\n", l.sprint(l.Formatter.CommentColor, "←"),
+		l.sprint(statusColor(status), fmt.Sprintf("%d %s", status, http.StatusText(status))),
+		l.sprint(l.Formatter.CommentColor, elapsed.Round(time.Millisecond).String()))
+	l.writeBody(respBody)
+	w.Flush()
+}
+
+func (l *Logger) logError(method, target string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.Formatter.Buffer
+	fmt.Fprintf(w, "%s %s %s\n", l.sprint(l.Formatter.CommentColor, "→"), method, target)
+	fmt.Fprintf(w, "%s %s\n", l.sprint(l.Formatter.CommentColor, "←"), l.sprint(l.Formatter.ErrorColor, err.Error()))
+	w.Flush()
+}
+
+// writeBody writes a captured body, or a note saying why it isn't shown.
+func (l *Logger) writeBody(b body) {
+	w := l.Formatter.Buffer
+	switch {
+	case b.truncated:
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(body over %d bytes not shown)", l.MaxBody)))
+	case b.unread:
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, "(body closed before its end)"))
+	case b.data == nil && b.size != 0:
+		if b.size > 0 {
+			fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(%d bytes, not JSON)", b.size)))
+		} else {
+			fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, "(body not JSON)"))
+		}
+	case len(bytes.TrimSpace(b.data)) > 0:
+		s := &colorjson.Stream{Formatter: l.Formatter}
+		if err := s.Copy(bytes.NewReader(b.data)); err != nil {
+			fmt.Fprintln(w, l.sprint(l.Formatter.ErrorColor, "(invalid JSON: "+err.Error()+")"))
+		}
+	}
+}
+
+func (l *Logger) sprint(c color.PrinterFace, s string) string {
+	if l.Formatter.DisabledColor || c == nil {
+		return s
+	}
+	return c.Sprint(s)
+}
+
+func statusColor(status int) color.PrinterFace {
+	switch {
+	case status >= 500:
+		return color.FgRed
+	case status >= 400:
+		return color.FgYellow
+	case status >= 300:
+		return color.FgCyan
+	}
+	return color.FgGreen
+}
+
+// recorder is a ResponseWriter keeping the status and the start of the
+// body.
+type recorder struct {
+	http.ResponseWriter
+	status    int
+	max       int
+	body      bytes.Buffer
+	size      int64
+	truncated bool
+	wroteHead bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHead {
+		r.status = status
+		r.wroteHead = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.wroteHead = true
+	if room := r.max - r.body.Len(); room >= len(p) && !r.truncated {
+		r.body.Write(p)
+	} else {
+		r.truncated = true
+	}
+	r.size += int64(len(p))
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush lets handlers stream through the middleware.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers take over the connection, as WebSockets do.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httpdump: %T can't be hijacked", r.ResponseWriter)
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the ResponseWriter r wraps, for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,200 @@
+package httpdump
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// elapsed matches the request duration, which varies between runs.
+var elapsed = regexp.MustCompile(`[0-9.]+[µnm]?s\n`)
+
+func newLogger(buf *bytes.Buffer) *Logger {
+	l := New(buf)
+	l.Formatter.DisabledColor = true
+	l.Formatter.ExcludeKeys = []string{"password"}
+	return l
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if string(data) != `{"user":"ann","password":"x"}` {
+			t.Errorf("handler read %q", data)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1,"user":"ann"}`))
+	}))
+
+	r := httptest.NewRequest("POST", "/users?x=1", strings.NewReader(`{"user":"ann","password":"x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Body.String() != `{"id":1,"user":"ann"}` || w.Code != http.StatusCreated {
+		t.Errorf("response changed: %d %q", w.Code, w.Body.String())
+	}
+	want := "→ POST /users?x=1\n" +
+		`{ "user": "ann" }` + "\n" +
+		"← 201 Created \n" +
+		`{ "id": 1, "user": "ann" }` + "\n"
+	if got := elapsed.ReplaceAllString(buf.String(), "\n"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>hi</p>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"title":"not found","detail":"` + strings.Repeat("x", 100) + `"}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	l.MaxBody = 50
+	client := &http.Client{Transport: l.Transport(nil)}
+
+	resp, err := client.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasSuffix(string(data), strings.Repeat("x", 100)+`"}`) {
+		t.Errorf("client read %q", data)
+	}
+
+	resp, err = client.Get(srv.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	got := elapsed.ReplaceAllString(buf.String(), "\n")
+	want := "→ GET " + srv.URL + "/missing\n" +
+		"← 404 Not Found \n" +
+		"(body over 50 bytes not shown)\n" +
+		"→ GET " + srv.URL + "/page\n" +
+		"← 200 OK \n" +
+		"(body not JSON)\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReverseProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	proxy := httptest.NewServer(l.ReverseProxy(target))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/v1/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != `{"path":"/v1/items"}` {
+		t.Errorf("client read %q", data)
+	}
+
+	want := "→ GET " + upstream.URL + "/v1/items\n" +
+		"← 200 OK \n" +
+		`{ "path": "/v1/items" }` + "\n"
+	if got := elapsed.ReplaceAllString(buf.String(), "\n"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	srv := httptest.NewServer(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() == nil {
+			t.Error("recorder doesn't unwrap")
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nhi")
+		rw.Flush()
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "hi" {
+		t.Errorf("client read %q", data)
+	}
+}
+
+func TestTransportStream(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"n":1}` + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+			t.Error("the first line didn't reach the client")
+		}
+		w.Write([]byte(`{"n":2}` + "\n"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	client := &http.Client{Transport: l.Transport(nil)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(resp.Body)
+	if line, _ := br.ReadString('\n'); line != `{"n":1}`+"\n" {
+		t.Errorf("client read %q", line)
+	}
+	close(release)
+	io.ReadAll(br)
+	resp.Body.Close()
+
+	want := "→ GET " + srv.URL + "\n" +
+		"← 200 OK \n" +
+		`{ "n": 1 }` + "\n" +
+		`{ "n": 2 }` + "\n"
+	if got := elapsed.ReplaceAllString(buf.String(), "\n"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
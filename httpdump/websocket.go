@@ -0,0 +1,101 @@
+package httpdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/olebeck/colorjson"
+)
+
+// Direction is the direction of a WebSocket message.
+type Direction int
+
+const (
+	// Sent is a message sent by this side of the connection.
+	Sent Direction = iota
+	// Received is a message received from the peer.
+	Received
+)
+
+// WebSocket message types, as in RFC 6455 and gorilla/websocket.
+const (
+	textMessage   = 1
+	binaryMessage = 2
+)
+
+// MessageConn is a WebSocket connection reading and writing whole
+// messages, such as a *websocket.Conn of gorilla/websocket.
+type MessageConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+}
+
+// Tap returns conn with every data message read from or written to it
+// logged.
+func (l *Logger) Tap(conn MessageConn) MessageConn {
+	return &tappedConn{MessageConn: conn, l: l}
+}
+
+type tappedConn struct {
+	MessageConn
+	l *Logger
+}
+
+func (c *tappedConn) ReadMessage() (int, []byte, error) {
+	messageType, p, err := c.MessageConn.ReadMessage()
+	if err == nil {
+		c.l.logWebSocket(Received, messageType, p)
+	}
+	return messageType, p, err
+}
+
+func (c *tappedConn) WriteMessage(messageType int, data []byte) error {
+	c.l.logWebSocket(Sent, messageType, data)
+	return c.MessageConn.WriteMessage(messageType, data)
+}
+
+// LogMessage writes a WebSocket text message with its direction and the
+// time, for connections Tap can't wrap: call it from their frame
+// callbacks.
+func (l *Logger) LogMessage(dir Direction, data []byte) {
+	l.logWebSocket(dir, textMessage, data)
+}
+
+func (l *Logger) logWebSocket(dir Direction, messageType int, data []byte) {
+	if messageType != textMessage && messageType != binaryMessage {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	arrow := "→"
+	if dir == Received {
+		arrow = "←"
+	}
+	w := l.Formatter.Buffer
+	fmt.Fprintf(w, "%s %s ", l.sprint(l.Formatter.CommentColor, l.clock().Format("15:04:05.000")), l.sprint(l.Formatter.CommentColor, arrow))
+	switch {
+	case messageType == binaryMessage:
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(%d bytes binary)", len(data))))
+	case len(data) > l.MaxBody:
+		fmt.Fprintln(w, l.sprint(l.Formatter.CommentColor, fmt.Sprintf("(message over %d bytes not shown)", l.MaxBody)))
+	case json.Valid(data):
+		s := &colorjson.Stream{Formatter: l.Formatter}
+		if err := s.Copy(bytes.NewReader(data)); err != nil {
+			fmt.Fprintln(w, l.sprint(l.Formatter.ErrorColor, "(invalid JSON: "+err.Error()+")"))
+		}
+	default:
+		fmt.Fprintf(w, "%s\n", data)
+	}
+	w.Flush()
+}
+
+func (l *Logger) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
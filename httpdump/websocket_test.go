@@ -0,0 +1,52 @@
+package httpdump
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakeConn reads the messages in in and records those written.
+type fakeConn struct {
+	in  [][]byte
+	out [][]byte
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	p := c.in[0]
+	c.in = c.in[1:]
+	return textMessage, p, nil
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	c.out = append(c.out, data)
+	return nil
+}
+
+func TestTap(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf)
+	l.now = func() time.Time { return time.Date(2024, 5, 1, 12, 3, 1, 250e6, time.UTC) }
+
+	fake := &fakeConn{in: [][]byte{[]byte(`{"type":"ack","id":1}`)}}
+	conn := l.Tap(fake)
+	if err := conn.WriteMessage(textMessage, []byte(`{"type":"subscribe","password":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != `{"type":"ack","id":1}` {
+		t.Fatalf("read %q, %v", p, err)
+	}
+	conn.WriteMessage(binaryMessage, []byte{1, 2, 3})
+	l.LogMessage(Received, []byte("ping"))
+
+	if len(fake.out) != 2 || string(fake.out[0]) != `{"type":"subscribe","password":"x"}` {
+		t.Errorf("wrote %q", fake.out)
+	}
+	want := `12:03:01.250 → { "type": "subscribe" }` + "\n" +
+		`12:03:01.250 ← { "type": "ack", "id": 1 }` + "\n" +
+		"12:03:01.250 → (3 bytes binary)\n" +
+		"12:03:01.250 ← ping\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
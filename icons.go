@@ -0,0 +1,67 @@
+package colorjson
+
+import (
+	"bufio"
+
+	"github.com/gookit/color"
+)
+
+// IconSet holds the glyphs written before each kind of element when the
+// formatter's Icons is set. Empty glyphs are left out.
+type IconSet struct {
+	Key    string
+	String string
+	Number string
+	Bool   string
+	Null   string
+	Object string
+	Array  string
+}
+
+// NerdFontIcons needs a terminal font patched by Nerd Fonts.
+var NerdFontIcons = IconSet{
+	Key:    "\uf084", // nf-fa-key
+	String: "\uf10d", // nf-fa-quote_left
+	Number: "\uf292", // nf-fa-hashtag
+	Bool:   "\uf205", // nf-fa-toggle_on
+	Null:   "\uf05e", // nf-fa-ban
+	Object: "\ueb0f", // nf-cod-json
+	Array:  "\uea8a", // nf-cod-symbol_array
+}
+
+// ASCIIIcons is the fallback for any terminal and font.
+var ASCIIIcons = IconSet{
+	Key:    "@",
+	String: "$",
+	Number: "#",
+	Bool:   "?",
+	Null:   "~",
+	Object: "%",
+	Array:  "*",
+}
+
+// icon returns glyph colored with c and followed by a space, or "" if icons
+// are off.
+func (f *Formatter) icon(glyph func(s *IconSet) string, c color.PrinterFace) string {
+	if f.Icons == nil || glyph(f.Icons) == "" {
+		return ""
+	}
+	return f.sprintColor(c, glyph(f.Icons)) + " "
+}
+
+// writeIcon writes the icon of a container.
+func (f *Formatter) writeIcon(glyph func(s *IconSet) string, w *bufio.Writer) (int, error) {
+	s := f.icon(glyph, f.colorOf(ElementPunctuation, f.BackColor))
+	if s == "" {
+		return 0, nil
+	}
+	return w.WriteString(s)
+}
+
+func keyIcon(s *IconSet) string    { return s.Key }
+func stringIcon(s *IconSet) string { return s.String }
+func numberIcon(s *IconSet) string { return s.Number }
+func boolIcon(s *IconSet) string   { return s.Bool }
+func nullIcon(s *IconSet) string   { return s.Null }
+func objectIcon(s *IconSet) string { return s.Object }
+func arrayIcon(s *IconSet) string  { return s.Array }
@@ -0,0 +1,177 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ImagePreview selects how string values holding base64 images are
+// previewed.
+type ImagePreview int
+
+const (
+	// ImagePreviewOff leaves image strings alone.
+	ImagePreviewOff ImagePreview = iota
+	// ImagePreviewAuto uses the terminal's image protocol when it is known
+	// to support one and annotates the image otherwise.
+	ImagePreviewAuto
+	// ImagePreviewITerm2 uses the iTerm2 inline image protocol.
+	ImagePreviewITerm2
+	// ImagePreviewKitty uses the kitty graphics protocol (PNG only).
+	ImagePreviewKitty
+	// ImagePreviewAnnotate only notes the image type and size.
+	ImagePreviewAnnotate
+)
+
+const defaultImagePreviewMaxBytes = 64 << 10
+
+// previewImageRows is the height of inline previews in terminal rows.
+const previewImageRows = 3
+
+const kittyChunkSize = 4096
+
+// detectImagePreview picks a protocol from the environment of the current
+// process.
+func detectImagePreview() ImagePreview {
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty":
+		return ImagePreviewKitty
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm":
+		return ImagePreviewITerm2
+	}
+	return ImagePreviewAnnotate
+}
+
+// decodeImage returns the MIME type and size of the image s holds as a
+// data URI or plain base64, if it is a PNG or JPEG, and its bytes unless
+// there are more than max.
+func decodeImage(s string, max int) ([]byte, string, int, bool) {
+	if strings.HasPrefix(s, "data:image/") {
+		i := strings.Index(s, ";base64,")
+		if i < 0 {
+			return nil, "", 0, false
+		}
+		s = s[i+len(";base64,"):]
+	}
+
+	// Both magic numbers fit in the first 8 decoded bytes.
+	if len(s) < 12 {
+		return nil, "", 0, false
+	}
+	head, err := base64.StdEncoding.DecodeString(s[:12])
+	if err != nil {
+		return nil, "", 0, false
+	}
+
+	var mime string
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		mime = "image/png"
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		mime = "image/jpeg"
+	default:
+		return nil, "", 0, false
+	}
+
+	// Images too large to show are measured without decoding them whole.
+	if base64.StdEncoding.DecodedLen(len(s)) > max {
+		n, err := io.Copy(io.Discard, base64.NewDecoder(base64.StdEncoding, strings.NewReader(s)))
+		if err != nil {
+			return nil, "", 0, false
+		}
+		if n > int64(max) {
+			return nil, mime, int(n), true
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, "", 0, false
+	}
+	return data, mime, len(data), true
+}
+
+// writeImagePreview follows a string value with a preview of the image it
+// holds, if any.
+func (f *Formatter) writeImagePreview(s string, w *bufio.Writer) (int, error) {
+	if f.ImagePreview == ImagePreviewOff {
+		return 0, nil
+	}
+
+	max := f.ImagePreviewMaxBytes
+	if max == 0 {
+		max = defaultImagePreviewMaxBytes
+	}
+	data, mime, size, ok := decodeImage(s, max)
+	if !ok {
+		return 0, nil
+	}
+
+	protocol := f.ImagePreview
+	if protocol == ImagePreviewAuto {
+		protocol = detectImagePreview()
+	}
+	if f.DisabledColor || size > max || (protocol == ImagePreviewKitty && mime != "image/png") {
+		protocol = ImagePreviewAnnotate
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch protocol {
+	case ImagePreviewITerm2:
+		return fmt.Fprintf(w, " \x1b]1337;File=inline=1;size=%d;height=%d;preserveAspectRatio=1:%s\a", len(data), previewImageRows, encoded)
+	case ImagePreviewKitty:
+		return writeKittyImage(w, encoded)
+	}
+	return w.WriteString(" " + f.sprintfColor(f.CommentColor, "/* %s, %s */", mime, formatBytes(size)))
+}
+
+// writeKittyImage transmits and displays a PNG in chunks as the kitty
+// graphics protocol requires.
+func writeKittyImage(w *bufio.Writer, encoded string) (int, error) {
+	wr, err := w.WriteString(" ")
+	if err != nil {
+		return wr, err
+	}
+
+	first := true
+	for len(encoded) > 0 {
+		chunk := encoded
+		if len(chunk) > kittyChunkSize {
+			chunk = chunk[:kittyChunkSize]
+		}
+		encoded = encoded[len(chunk):]
+
+		more := 0
+		if len(encoded) > 0 {
+			more = 1
+		}
+
+		var n int
+		if first {
+			n, err = fmt.Fprintf(w, "\x1b_Ga=T,f=100,r=%d,m=%d;%s\x1b\\", previewImageRows, more, chunk)
+			first = false
+		} else {
+			n, err = fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+		wr += n
+		if err != nil {
+			return wr, err
+		}
+	}
+	return wr, nil
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,58 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+var errTooWide = errors.New("colorjson: too wide to inline")
+
+// inlineBufferSize keeps the trial rendering of a container small, so a
+// large container is given up on early.
+const inlineBufferSize = 64
+
+// widthLimit counts the width of what is written to it and fails once it
+// reaches max.
+type widthLimit struct {
+	max   int
+	width int
+}
+
+func (l *widthLimit) Write(p []byte) (int, error) {
+	l.width += utf8.RuneCount(p)
+	if l.width >= l.max {
+		return 0, errTooWide
+	}
+	return len(p), nil
+}
+
+// writeInlineOr writes a container on one line if InlineShort is set and
+// it is narrower than that many columns, and with write otherwise.
+func (f *Formatter) writeInlineOr(write func(w *bufio.Writer) (int, error), w *bufio.Writer) (int, error) {
+	if f.InlineShort <= 0 || f.Indent == 0 {
+		return write(w)
+	}
+
+	var buf bytes.Buffer
+	trial := bufio.NewWriterSize(io.MultiWriter(&buf, &stripWriter{w: &widthLimit{max: f.InlineShort}}), inlineBufferSize)
+
+	indent, stats := f.Indent, f.stats
+	f.Indent = 0
+	_, err := write(trial)
+	if err == nil {
+		err = trial.Flush()
+	}
+	f.Indent = indent
+
+	if err == errTooWide {
+		f.stats = stats
+		return write(w)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(buf.Bytes())
+}
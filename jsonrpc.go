@@ -0,0 +1,205 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// RPCDirection tags the JSON-RPC messages CopyJSONRPC writes with who sent
+// them.
+type RPCDirection int
+
+const (
+	// RPCInferred takes requests and notifications as sent and responses
+	// as received, as seen by a client.
+	RPCInferred RPCDirection = iota
+	// RPCSent marks every message as sent: →.
+	RPCSent
+	// RPCReceived marks every message as received: ←.
+	RPCReceived
+)
+
+// RPCMethodColor is the color of method names in JSON-RPC messages.
+var RPCMethodColor color.PrinterFace = Style{Color: color.FgBlue, Bold: true}
+
+// CopyJSONRPC colorizes JSON-RPC 2.0 messages read from r, either framed by
+// Content-Length headers as in the Language Server Protocol or one after
+// another. Each message follows a line with its direction, its method and
+// its id; responses are paired with their request by id to show which
+// method they answer. Method names and error objects stand out in the
+// messages.
+//
+// Both directions of a connection can be copied to s at once, one per
+// goroutine, to see the conversation as it happens.
+func (s *Stream) CopyJSONRPC(r io.Reader, dir RPCDirection) error {
+	br := bufio.NewReader(r)
+	for {
+		b, err := peekNonSpace(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b == '{' || b == '[' {
+			return decodeAll(br, func(v interface{}) error {
+				return s.writeRPC(v, dir)
+			})
+		}
+
+		data, err := readFramed(br)
+		if err != nil {
+			return err
+		}
+		v, ok := decodeSingle(string(data))
+		if !ok {
+			return fmt.Errorf("colorjson: JSON-RPC message is not JSON: %.40q", data)
+		}
+		if err := s.writeRPC(v, dir); err != nil {
+			return err
+		}
+	}
+}
+
+// peekNonSpace skips white space and returns the next byte without
+// consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		br.Discard(1)
+	}
+}
+
+// readFramed reads the headers of a message and the content they announce.
+func readFramed(br *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("colorjson: bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("colorjson: JSON-RPC message without Content-Length")
+	}
+
+	data := make([]byte, length)
+	_, err := io.ReadFull(br, data)
+	return data, err
+}
+
+// writeRPC writes a message, or each message of a batch, after its tag.
+func (s *Stream) writeRPC(v interface{}, dir RPCDirection) error {
+	s.rpcMu.Lock()
+	defer s.rpcMu.Unlock()
+
+	if batch, ok := v.([]interface{}); ok {
+		for _, m := range batch {
+			if err := s.writeRPCMessage(m, dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return s.writeRPCMessage(v, dir)
+}
+
+func (s *Stream) writeRPCMessage(v interface{}, dir RPCDirection) error {
+	o, ok := v.(*object)
+	if !ok {
+		return s.writeRecord(v)
+	}
+
+	method, hasMethod := o.get("method")
+	name, _ := method.(string)
+	id, hasID := o.get("id")
+	idText, _ := json.Marshal(id)
+	if dir == RPCInferred {
+		dir = RPCSent
+		if !hasMethod {
+			dir = RPCReceived
+		}
+	}
+	if s.rpcMethods == nil {
+		s.rpcMethods = map[string]string{}
+	}
+
+	arrow := "→"
+	if dir == RPCReceived {
+		arrow = "←"
+	}
+	tag := []string{s.sprintColor(s.CommentColor, arrow)}
+	switch {
+	case hasMethod && hasID:
+		s.rpcMethods[arrow+string(idText)] = name
+		tag = append(tag, s.sprintColor(RPCMethodColor, name), s.sprintColor(s.CommentColor, "#"+string(idText)))
+	case hasMethod:
+		tag = append(tag, s.sprintColor(RPCMethodColor, name), s.sprintColor(s.CommentColor, "(notification)"))
+	default:
+		// The request went the other way.
+		request := "←"
+		if dir == RPCReceived {
+			request = "→"
+		}
+		if name, ok := s.rpcMethods[request+string(idText)]; ok {
+			delete(s.rpcMethods, request+string(idText))
+			tag = append(tag, s.sprintColor(RPCMethodColor, name))
+		}
+		tag = append(tag, s.sprintColor(s.CommentColor, "#"+string(idText)))
+		if e, ok := o.get("error"); ok {
+			label := "error"
+			if e, ok := e.(*object); ok {
+				if code, ok := e.get("code"); ok {
+					label += " " + fmt.Sprint(code)
+				}
+			}
+			tag = append(tag, s.sprintColor(s.ErrorColor, label))
+		}
+	}
+	if _, err := s.Buffer.WriteString(strings.Join(tag, " ") + "\n"); err != nil {
+		return err
+	}
+
+	prepare := s.prepare
+	defer func() { s.prepare = prepare }()
+	s.prepare = func(f *Formatter, v interface{}) {
+		if prepare != nil {
+			prepare(f, v)
+		}
+		valueColor := f.ValueColor
+		f.ValueColor = func(path, value string) color.PrinterFace {
+			switch {
+			case path == rootPath+".method":
+				return RPCMethodColor
+			case strings.HasPrefix(path, rootPath+".error."):
+				return f.ErrorColor
+			case valueColor != nil:
+				return valueColor(path, value)
+			}
+			return nil
+		}
+	}
+	return s.writeRecord(v)
+}
@@ -0,0 +1,80 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"strings"
+)
+
+// decodeJWT returns the header and claims of s if it is a JSON Web Token.
+// The signature is not checked.
+func decodeJWT(s string) (header, claims interface{}, ok bool) {
+	// Every header starts with `{"`, which is "eyJ" in base64.
+	if !strings.HasPrefix(s, "eyJ") {
+		return nil, nil, false
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, nil, false
+	}
+	header, ok = decodeJWTPart(parts[0])
+	if !ok {
+		return nil, nil, false
+	}
+	if h, isObject := header.(*object); !isObject {
+		return nil, nil, false
+	} else if _, hasAlg := h.get("alg"); !hasAlg {
+		return nil, nil, false
+	}
+	claims, ok = decodeJWTPart(parts[1])
+	return header, claims, ok
+}
+
+// decodeJWTPart decodes a base64url encoded JSON part of a token.
+func decodeJWTPart(s string) (interface{}, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, false
+	}
+	return decodeSingle(string(data))
+}
+
+// writeJWTPreview follows a string value with the decoded header and claims
+// of the token it holds, if any.
+func (f *Formatter) writeJWTPreview(s string, w *bufio.Writer) (int, error) {
+	if !f.JWTPreview {
+		return 0, nil
+	}
+	header, claims, ok := decodeJWT(s)
+	if !ok {
+		return 0, nil
+	}
+	return w.WriteString(" " + f.sprintColor(f.CommentColor, "/* JWT, signature not verified: header") +
+		" " + escapeComment(f.inlineJSON(header)) + " " + f.sprintColor(f.CommentColor, "claims") +
+		" " + escapeComment(f.inlineJSON(claims)) + " " + f.sprintColor(f.CommentColor, "*/"))
+}
+
+// escapeComment keeps decoded text from ending the comment it is shown in,
+// writing */ as *\/.
+func escapeComment(s string) string {
+	return strings.ReplaceAll(s, "*/", `*\/`)
+}
+
+// inlineJSON returns v, a decoded document, colorized on one line, for
+// annotations.
+func (f *Formatter) inlineJSON(v interface{}) string {
+	var buf bytes.Buffer
+	g := f.clone(&buf)
+	g.Indent = 0
+	g.JWTPreview = false
+	g.Base64Preview = false
+	g.QueryStringPreview = false
+	g.lines = nil
+	g.OnProgress = nil
+	if _, err := g.marshalNode(node{plain: v, fast: true}, g.Buffer, initialDepth); err != nil {
+		return ""
+	}
+	g.Buffer.Flush()
+	return buf.String()
+}
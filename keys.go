@@ -0,0 +1,96 @@
+package colorjson
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase turns a Go identifier such as "UserID" into "user_id".
+func SnakeCase(s string) string {
+	return strings.Join(lowerWords(s), "_")
+}
+
+// KebabCase turns a Go identifier such as "UserID" into "user-id".
+func KebabCase(s string) string {
+	return strings.Join(lowerWords(s), "-")
+}
+
+// CamelCase turns a Go identifier such as "UserID" into "userId".
+func CamelCase(s string) string {
+	words := lowerWords(s)
+	for i := 1; i < len(words); i++ {
+		r := []rune(words[i])
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, "")
+}
+
+func lowerWords(s string) []string {
+	words := splitWords(s)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return words
+}
+
+// splitWords splits an identifier on underscores, dashes and case changes,
+// keeping acronyms together: "HTTPServer_ID" becomes "HTTP", "Server", "ID".
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+		start = end
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush(i)
+			}
+		}
+	}
+	flush(len(runes))
+
+	return words
+}
+
+// isIdentifier reports whether s can be used as an unquoted JavaScript/JSON5
+// object key.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '$':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// keyRank returns the position of key in order, which KeyOrder writes it
+// at, or len(order) if it isn't listed.
+func keyRank(order []string, key string) int {
+	for i, k := range order {
+		if k == key {
+			return i
+		}
+	}
+	return len(order)
+}
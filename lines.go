@@ -0,0 +1,82 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+)
+
+const sgrReset = "\x1b[0m"
+
+// OnLine makes f deliver its output to fn one line at a time, without the
+// line break, instead of writing it to a writer. A color that is still set
+// at the end of a line is reset there and set again at the start of the
+// next one, so every line can be shown on its own. The line is only valid
+// during the call.
+func (f *Formatter) OnLine(fn func(line []byte)) {
+	f.lines = &lineWriter{fn: fn}
+	f.Buffer = bufio.NewWriter(f.lines)
+}
+
+// lineWriter collects output until a line is complete.
+type lineWriter struct {
+	fn   func(line []byte)
+	line []byte
+	// sgr is the last color sequence written on the current line, or nil if
+	// none is in effect.
+	sgr []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			l.line = append(l.line, p...)
+			return n, nil
+		}
+		l.line = append(l.line, p[:i]...)
+		p = p[i+1:]
+		l.emit()
+	}
+}
+
+// flush delivers a final line that has no line break.
+func (l *lineWriter) flush() {
+	if len(l.line) > 0 {
+		l.emit()
+	}
+}
+
+func (l *lineWriter) emit() {
+	l.trackSGR(l.line)
+	if l.sgr != nil {
+		l.line = append(l.line, sgrReset...)
+	}
+	l.fn(l.line)
+
+	l.line = append(l.line[:0], l.sgr...)
+}
+
+// trackSGR updates sgr from the color sequences in line.
+func (l *lineWriter) trackSGR(line []byte) {
+	for {
+		i := bytes.Index(line, []byte("\x1b["))
+		if i < 0 {
+			return
+		}
+		line = line[i:]
+		end := bytes.IndexByte(line, 'm')
+		if end < 0 || bytes.IndexFunc(line[2:end], func(r rune) bool { return r != ';' && (r < '0' || r > '9') }) >= 0 {
+			line = line[2:]
+			continue
+		}
+
+		seq := line[:end+1]
+		if string(seq) == sgrReset || string(seq) == "\x1b[m" {
+			l.sgr = nil
+		} else {
+			l.sgr = append(l.sgr[:0:0], seq...)
+		}
+		line = line[end+1:]
+	}
+}
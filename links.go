@@ -0,0 +1,39 @@
+package colorjson
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hyperlink wraps text in an OSC 8 hyperlink to target.
+func hyperlink(target, text string) string {
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// looksLikePath reports whether s is an absolute file path, Unix or Windows
+// style, without characters that rarely appear in real paths.
+func looksLikePath(s string) bool {
+	if len(s) < 2 || strings.ContainsAny(s, "\n\t\"<>|*?") {
+		return false
+	}
+	if strings.HasPrefix(s, "/") {
+		return !strings.HasPrefix(s, "//") && strings.Count(s, "/") > 1
+	}
+	return len(s) > 3 && isDriveLetter(s[0]) && s[1] == ':' && (s[2] == '\\' || s[2] == '/')
+}
+
+func isDriveLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// fileURL returns the file:// URL of path on this host.
+func fileURL(path string) string {
+	host, _ := os.Hostname()
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Host: host, Path: path}).String()
+}
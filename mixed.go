@@ -0,0 +1,82 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// CopyMixed copies log text from r, colorizing the JSON objects and arrays
+// embedded in its lines, such as the payload in
+// `2024-05-01 INFO payload={"a":1}`, and passing everything else through
+// untouched. The JSON is written on one line, whatever Indent is, so line
+// structure is kept.
+func (s *Stream) CopyMixed(r io.Reader) error {
+	f := s.Formatter.clone(nil)
+	f.Indent = 0
+	f.TerminateWithNewline = false
+	f.lines = nil
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if _, werr := s.Buffer.WriteString(f.colorizeEmbedded(line)); werr != nil {
+				return werr
+			}
+			if werr := s.Buffer.Flush(); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// colorizeEmbedded returns line with the JSON objects and arrays in it
+// colorized.
+func (f *Formatter) colorizeEmbedded(line string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexAny(line, "{[")
+		if i < 0 {
+			b.WriteString(line)
+			return b.String()
+		}
+		b.WriteString(line[:i])
+		line = line[i:]
+
+		v, n, ok := decodePrefix(line)
+		if !ok {
+			b.WriteString(line[:1])
+			line = line[1:]
+			continue
+		}
+		var buf bytes.Buffer
+		g := f.clone(&buf)
+		if err := g.encode(node{plain: v, fast: true}); err != nil {
+			b.WriteString(line[:n])
+		} else {
+			b.WriteString(buf.String())
+		}
+		line = line[n:]
+	}
+}
+
+// decodePrefix decodes the JSON value at the start of s and returns it
+// with its length.
+func decodePrefix(s string) (interface{}, int, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, 0, false
+	}
+	return v, int(dec.InputOffset()), true
+}
@@ -0,0 +1,142 @@
+package colorjson
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+const refKey = "$ref"
+
+// OpenAPI sets s up for OpenAPI and Swagger documents: $ref values stand
+// out and link to what they point at, operations are colored by their HTTP
+// method and responses by their status code.
+func OpenAPI(s *Stream) {
+	s.KeyOrder = []string{refKey, "openapi", "swagger", "info", "servers", "paths", "components"}
+	s.KeyStyles = map[string]color.PrinterFace{
+		refKey:              color.FgBlue,
+		"paths.*":           Style{Bold: true},
+		"paths.*.get":       color.FgBlue,
+		"paths.*.head":      color.FgCyan,
+		"paths.*.options":   color.FgCyan,
+		"paths.*.post":      color.FgGreen,
+		"paths.*.put":       color.FgYellow,
+		"paths.*.patch":     color.FgYellow,
+		"paths.*.delete":    color.FgRed,
+		"responses.1??":     color.FgCyan,
+		"responses.2??":     color.FgGreen,
+		"responses.3??":     color.FgCyan,
+		"responses.4??":     color.FgYellow,
+		"responses.5??":     color.FgRed,
+		"responses.default": color.C256(244),
+	}
+	s.ValueColor = func(path, value string) color.PrinterFace {
+		if strings.HasSuffix(path, "."+refKey) {
+			return Style{Color: color.FgBlue, Underline: true}
+		}
+		return nil
+	}
+	s.linkOf = func(path, value string) string {
+		if strings.HasSuffix(path, "."+refKey) {
+			return value
+		}
+		return ""
+	}
+}
+
+// OpenAPIResolved is OpenAPI with every local $ref, such as
+// "#/components/schemas/Pet", replaced by what it points at, next to the
+// $ref itself. References back into a schema being resolved are left
+// alone.
+func OpenAPIResolved(s *Stream) {
+	OpenAPI(s)
+	s.prepare = func(f *Formatter, v interface{}) {
+		resolveRefs(v, v, nil)
+	}
+}
+
+// resolveRefs resolves the local references in v, a value in root, in
+// place and returns v. stack holds the references being resolved.
+func resolveRefs(v, root interface{}, stack []string) interface{} {
+	switch v := v.(type) {
+	case *object:
+		if ref, ok := v.get(refKey); ok {
+			if ref, ok := ref.(string); ok && strings.HasPrefix(ref, "#") && !contains(stack, ref) {
+				if target, ok := lookupPointer(root, ref[1:]).(*object); ok {
+					resolved := &object{keys: []string{refKey}, values: []interface{}{ref}}
+					for i, key := range target.keys {
+						if key != refKey {
+							resolved.keys = append(resolved.keys, key)
+							resolved.values = append(resolved.values, resolveRefs(copyValue(target.values[i]), root, append(stack, ref)))
+						}
+					}
+					return resolved
+				}
+			}
+		}
+		for i := range v.values {
+			v.values[i] = resolveRefs(v.values[i], root, stack)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = resolveRefs(v[i], root, stack)
+		}
+	}
+	return v
+}
+
+// lookupPointer returns what the JSON pointer p points at in v, or nil.
+func lookupPointer(v interface{}, p string) interface{} {
+	if p == "" {
+		return v
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil
+	}
+	for _, token := range strings.Split(p[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch cur := v.(type) {
+		case *object:
+			v, _ = cur.get(token)
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(cur) {
+				return nil
+			}
+			v = cur[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// copyValue returns a deep copy of v, so resolving references in one place
+// leaves the others as they are.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *object:
+		c := &object{keys: append([]string(nil), v.keys...), values: make([]interface{}, len(v.values))}
+		for i, value := range v.values {
+			c.values[i] = copyValue(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, value := range v {
+			c[i] = copyValue(value)
+		}
+		return c
+	}
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package colorjson
+
+// Option overrides formatter settings for a single Encode call.
+type Option func(f *Formatter)
+
+// Compact writes the document on one line.
+func Compact() Option {
+	return func(f *Formatter) { f.Indent = 0 }
+}
+
+// NoColor writes the document without colors.
+func NoColor() Option {
+	return func(f *Formatter) { f.DisabledColor = true }
+}
+
+// WithIndent indents the document by n spaces per level.
+func WithIndent(n int) Option {
+	return func(f *Formatter) { f.Indent = n }
+}
+
+// withOptions returns f itself if there are no options, and otherwise a copy
+// of f, writing to the same buffer, with the options applied.
+func (f *Formatter) withOptions(opts []Option) *Formatter {
+	if len(opts) == 0 {
+		return f
+	}
+
+	c := f.clone(nil)
+	c.Buffer = f.Buffer
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Monochrome writes the document with the "monochrome" theme, emphasizing
+// structure with bold, underline and faint text and no colors at all.
+// Highlights and annotations lose their colors too.
+func Monochrome() Option {
+	return func(f *Formatter) {
+		f.SetTheme(Themes["monochrome"])
+		f.UnexportedColor = Style{Faint: true, Italic: true}
+		f.FilteredColor = Style{Faint: true}
+		f.CommentColor = Style{Faint: true}
+		f.HighlightColor = Style{Bold: true, Underline: true}
+		f.FrameColor = Style{Faint: true}
+		f.FrameTitleColor = Style{Bold: true}
+		f.KeyStyles = nil
+		f.ValueColor = nil
+	}
+}
+
+// Deterministic makes the output byte-stable across runs and platforms, for
+// snapshot tests: map keys and struct fields are sorted, and nothing
+// depends on the terminal or environment, so colors, file links, inline
+// images and COLORJSON_HIGHLIGHT are off. Floats are always written as
+// encoding/json writes them, in the shortest form that round-trips at their
+// precision, which doesn't vary by platform.
+func Deterministic() Option {
+	return func(f *Formatter) {
+		f.SortKeys = true
+		f.DisabledColor = true
+		f.FileLinks = false
+		f.HighlightKeys = nil
+		if f.ImagePreview != ImagePreviewOff {
+			f.ImagePreview = ImagePreviewAnnotate
+		}
+	}
+}
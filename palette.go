@@ -0,0 +1,104 @@
+package colorjson
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// PaletteEnv set to "off" keeps NewFormatter and FromEnv from adjusting
+// their colors to the detected palette.
+const PaletteEnv = "COLORJSON_PALETTE"
+
+// Palette is a well-known terminal color palette.
+type Palette int
+
+const (
+	PaletteUnknown Palette = iota
+	// PaletteWindowsTerminal is the Campbell scheme of Windows Terminal.
+	PaletteWindowsTerminal
+	// PaletteWindowsConsole is the classic console host behind cmd.exe.
+	PaletteWindowsConsole
+	// PaletteAppleTerminal is macOS Terminal.app with its white background.
+	PaletteAppleTerminal
+)
+
+// DetectPalette guesses the palette of the terminal from the environment.
+func DetectPalette() Palette {
+	switch {
+	case os.Getenv("WT_SESSION") != "":
+		return PaletteWindowsTerminal
+	case os.Getenv("TERM_PROGRAM") == "Apple_Terminal":
+		return PaletteAppleTerminal
+	case runtime.GOOS == "windows" && os.Getenv("TERM") == "":
+		return PaletteWindowsConsole
+	}
+	return PaletteUnknown
+}
+
+// paletteFixes replaces the 16 colors that are hard to read on the default
+// background of a palette.
+var paletteFixes = map[Palette]map[color.Color]color.PrinterFace{
+	PaletteWindowsTerminal: {
+		color.FgBlue: color.FgLightBlue,
+	},
+	PaletteWindowsConsole: {
+		color.FgBlue:     color.FgLightBlue,
+		color.FgMagenta:  color.FgLightMagenta,
+		color.FgDarkGray: color.FgWhite,
+	},
+	PaletteAppleTerminal: {
+		color.FgWhite:       color.FgBlack,
+		color.FgLightWhite:  color.FgBlack,
+		color.FgYellow:      color.C256(136),
+		color.FgLightYellow: color.C256(136),
+		color.FgCyan:        color.C256(30),
+	},
+}
+
+// paletteAdjusted reports whether colors should be adjusted to the palette.
+func paletteAdjusted() bool {
+	return strings.ToLower(os.Getenv(PaletteEnv)) != "off"
+}
+
+// ForPalette returns t with the colors known to be unreadable on palette p
+// replaced.
+func (t Theme) ForPalette(p Palette) Theme {
+	fixes := paletteFixes[p]
+	for _, c := range []*color.PrinterFace{&t.Back, &t.Key, &t.String, &t.Bool, &t.Number, &t.Null, &t.Error} {
+		*c = fixPalette(*c, fixes)
+	}
+	return t
+}
+
+// adjustPalette replaces the colors of f like ForPalette does.
+func (f *Formatter) adjustPalette(p Palette) {
+	fixes := paletteFixes[p]
+	for _, c := range []*color.PrinterFace{&f.BackColor, &f.KeyColor, &f.StringColor, &f.BoolColor, &f.NumberColor, &f.NullColor, &f.ErrorColor} {
+		*c = fixPalette(*c, fixes)
+	}
+}
+
+func fixPalette(c color.PrinterFace, fixes map[color.Color]color.PrinterFace) color.PrinterFace {
+	switch c := c.(type) {
+	case color.Color:
+		if fixed, ok := fixes[c]; ok {
+			return fixed
+		}
+	case Style:
+		c.Color = fixPalette(c.Color, fixes)
+		return c
+	case color.Style:
+		fixed := make(color.Style, len(c))
+		for i, part := range c {
+			fixed[i] = part
+			if replacement, ok := fixes[part].(color.Color); ok {
+				fixed[i] = replacement
+			}
+		}
+		return fixed
+	}
+	return c
+}
@@ -0,0 +1,76 @@
+package colorjson
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// rootPath is the path of the top-level value.
+const rootPath = "$"
+
+// pushKey and pushIndex extend the path of the value being written; pop
+// restores it once the value is done.
+func (f *Formatter) pushKey(key string) {
+	f.path = append(f.path, "."+key)
+}
+
+func (f *Formatter) pushIndex(i int) {
+	f.path = append(f.path, "["+strconv.Itoa(i)+"]")
+}
+
+func (f *Formatter) pop() {
+	f.path = f.path[:len(f.path)-1]
+}
+
+// currentPath returns the path of the value being written, such as
+// "$.items[3].name".
+func (f *Formatter) currentPath() string {
+	return rootPath + strings.Join(f.path, "")
+}
+
+// splitPath splits "$.items[3].name" into ".items", "[3]" and ".name".
+func splitPath(p string) []string {
+	p = strings.TrimPrefix(p, rootPath)
+
+	var segments []string
+	for p != "" {
+		end := strings.IndexAny(p[1:], ".[")
+		if end < 0 {
+			segments = append(segments, p)
+			break
+		}
+		segments = append(segments, p[:end+1])
+		p = p[end+1:]
+	}
+	return segments
+}
+
+// matchPath reports whether the path segments match pattern, a path whose
+// segments may contain glob characters ("$.items[*].name"). If prefix is
+// true, segments only need to start with the pattern.
+func matchPath(pattern string, segments []string, prefix bool) bool {
+	want := splitPath(pattern)
+	if len(segments) < len(want) || (!prefix && len(segments) != len(want)) {
+		return false
+	}
+	for i, w := range want {
+		if !matchSegment(w, segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegment matches a single path segment. Index segments match exactly
+// or through "[*]"; key segments are matched as globs.
+func matchSegment(pattern, segment string) bool {
+	if strings.HasPrefix(pattern, "[") {
+		return (pattern == "[*]" && strings.HasPrefix(segment, "[")) || pattern == segment
+	}
+	if !strings.HasPrefix(segment, ".") {
+		return false
+	}
+	ok, _ := path.Match(pattern[1:], segment[1:])
+	return ok
+}
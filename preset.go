@@ -0,0 +1,132 @@
+package colorjson
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// Preset configures a Stream for a particular kind of document.
+type Preset func(s *Stream)
+
+// Presets are the presets by name, as the command line tool's -preset flag
+// takes them.
+var Presets = map[string]Preset{
+	"journald":   Journald,
+	"k8s":        Kubernetes,
+	"cloudtrail": CloudTrail,
+	"terraform":  Terraform,
+	"geojson":    func(s *Stream) { s.GeoJSON = true },
+	"openapi":    OpenAPI,
+	// openapi-resolved inlines what $refs point at.
+	"openapi-resolved": OpenAPIResolved,
+}
+
+// SyslogLevelColors maps syslog severities, as numbers 0 to 7 and by name,
+// to the color records of that severity are tinted with.
+var SyslogLevelColors = map[string]color.PrinterFace{
+	"0":       color.FgLightRed,
+	"1":       color.FgLightRed,
+	"2":       color.FgLightRed,
+	"3":       color.FgRed,
+	"4":       color.FgYellow,
+	"5":       color.FgCyan,
+	"6":       color.FgGreen,
+	"7":       color.C256(244),
+	"emerg":   color.FgLightRed,
+	"alert":   color.FgLightRed,
+	"crit":    color.FgLightRed,
+	"err":     color.FgRed,
+	"error":   color.FgRed,
+	"warning": color.FgYellow,
+	"notice":  color.FgCyan,
+	"info":    color.FgGreen,
+	"debug":   color.C256(244),
+}
+
+// Journald sets s up for the output of journalctl -o json: records are
+// tinted by their PRIORITY, and the fields saying what logged a record and
+// its MESSAGE come first and stand out.
+func Journald(s *Stream) {
+	s.LevelKey = "PRIORITY"
+	s.LevelColors = SyslogLevelColors
+	s.KeyOrder = []string{"__REALTIME_TIMESTAMP", "PRIORITY", "_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "_PID", "MESSAGE"}
+	s.KeyStyles = map[string]color.PrinterFace{
+		"MESSAGE":           Style{Color: color.FgLightWhite, Bold: true},
+		"PRIORITY":          Style{Bold: true},
+		"_SYSTEMD_UNIT":     color.FgCyan,
+		"SYSLOG_IDENTIFIER": color.FgCyan,
+		"_*":                color.C256(244),
+	}
+}
+
+// conditionStatus matches the path of the status of a Kubernetes condition.
+var conditionStatus = regexp.MustCompile(`\.conditions\[\d+\]\.status$`)
+
+// ConditionColors maps the status of a Kubernetes condition to its color.
+var ConditionColors = map[string]color.PrinterFace{
+	"True":    color.FgGreen,
+	"False":   color.FgRed,
+	"Unknown": color.FgYellow,
+}
+
+// Kubernetes sets s up for objects from kubectl get -o json: kind,
+// apiVersion, metadata, spec and status come first, in that order, and a
+// condition's type before its status. metadata.managedFields is collapsed
+// and the statuses of conditions are colored.
+func Kubernetes(s *Stream) {
+	s.KeyOrder = []string{"kind", "apiVersion", "type", "name", "namespace", "metadata", "spec", "status"}
+	s.KeyStyles = map[string]color.PrinterFace{
+		"kind":          Style{Color: color.FgCyan, Bold: true},
+		"metadata.name": Style{Bold: true},
+	}
+	s.FilterFunc = func(path string, v interface{}) bool {
+		return !strings.HasSuffix(path, ".metadata.managedFields")
+	}
+	s.ShowFiltered = true
+	s.ValueColor = func(path, value string) color.PrinterFace {
+		if conditionStatus.MatchString(path) {
+			return ConditionColors[value]
+		}
+		return nil
+	}
+}
+
+// CloudTrail sets s up for AWS CloudTrail events, such as the Records of a
+// CloudTrail log file, and CloudWatch Logs Insights results: what was done,
+// by whom and from where stands out, and failed events are tinted, those
+// denied for lack of permissions differently from other errors.
+func CloudTrail(s *Stream) {
+	s.KeyOrder = []string{"@timestamp", "eventTime", "eventSource", "eventName", "errorCode", "errorMessage", "userIdentity", "sourceIPAddress", "@message"}
+	s.KeyStyles = map[string]color.PrinterFace{
+		"eventName":        Style{Bold: true},
+		"errorCode":        Style{Color: color.FgRed, Bold: true},
+		"errorMessage":     color.FgRed,
+		"sourceIPAddress":  color.FgCyan,
+		"userIdentity.arn": color.FgCyan,
+	}
+	s.ValueColor = func(path, value string) color.PrinterFace {
+		switch {
+		case strings.HasSuffix(path, ".eventName"):
+			return Style{Color: color.FgLightWhite, Bold: true}
+		case strings.HasSuffix(path, ".errorCode"), strings.HasSuffix(path, ".errorMessage"):
+			return color.FgRed
+		}
+		return nil
+	}
+	s.LevelColors = map[string]color.PrinterFace{
+		"denied": color.FgMagenta,
+		"error":  color.FgRed,
+	}
+	s.levelOf = func(o *object) string {
+		code, ok := o.get("errorCode")
+		if !ok {
+			return ""
+		}
+		if code, _ := code.(string); strings.Contains(code, "Denied") || strings.Contains(code, "Unauthorized") || strings.Contains(code, "Forbidden") {
+			return "denied"
+		}
+		return "error"
+	}
+}
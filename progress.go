@@ -0,0 +1,52 @@
+package colorjson
+
+import "bufio"
+
+// progressInterval is the number of nodes between OnProgress calls.
+const progressInterval = 1000
+
+// countWriter counts what reaches the formatter's buffer while OnProgress
+// needs exact byte counts, passing it through right away.
+type countWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	if err != nil {
+		return n, err
+	}
+	return n, c.w.Flush()
+}
+
+// startProgress routes output through a countWriter if OnProgress is set
+// and returns a function undoing it.
+func (f *Formatter) startProgress() func() {
+	if f.OnProgress == nil {
+		return func() {}
+	}
+
+	out := f.Buffer
+	f.counter = &countWriter{w: out}
+	f.Buffer = bufio.NewWriter(f.counter)
+	return func() {
+		f.Buffer = out
+		f.counter = nil
+	}
+}
+
+// visit counts a node and reports progress every progressInterval nodes.
+func (f *Formatter) visit() {
+	f.stats.Nodes++
+	if f.OnProgress != nil && f.stats.Nodes%progressInterval == 0 {
+		f.reportProgress()
+	}
+}
+
+func (f *Formatter) reportProgress() {
+	if f.counter != nil {
+		f.OnProgress(f.counter.n+f.Buffer.Buffered(), f.stats.Nodes)
+	}
+}
@@ -0,0 +1,414 @@
+package colorjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Query is a compiled selection expression in a small subset of jq:
+//
+//	.                         the input itself
+//	.items .spec.name ."a b"  object members
+//	.[0] .items[2]            array elements
+//	.[] .items[]              every element of an array or value of an object
+//	select(.status=="failed") inputs for which the condition holds
+//	a | b                     feeds every result of a into b
+//
+// Conditions compare a path against a JSON literal with ==, !=, <, <=, >
+// or >=, or test a path for truthiness, and can be joined with and/or.
+type Query struct {
+	steps []queryStep
+}
+
+type queryStep func(v interface{}) []interface{}
+
+// ParseQuery compiles a query expression.
+func ParseQuery(query string) (*Query, error) {
+	p := &queryParser{src: query}
+	q := &Query{}
+
+	for {
+		step, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		q.steps = append(q.steps, step)
+
+		p.skipSpace()
+		if p.eof() {
+			return q, nil
+		}
+		if !p.consume("|") {
+			return nil, p.errorf("expected |")
+		}
+	}
+}
+
+// Run evaluates the query against v. Values that aren't already made of
+// map[string]interface{} and []interface{} are converted through
+// encoding/json first.
+func (q *Query) Run(v interface{}) ([]interface{}, error) {
+	v, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{v}
+	for _, step := range q.steps {
+		var next []interface{}
+		for _, r := range results {
+			next = append(next, step(r)...)
+		}
+		results = next
+	}
+	return results, nil
+}
+
+func toGeneric(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, bool, float64, string, json.Number, map[string]interface{}, []interface{}, *object:
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
+
+type queryParser struct {
+	src string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("colorjson: query %q at offset %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) peek(s string) bool {
+	p.skipSpace()
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *queryParser) consume(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseTerm() (queryStep, error) {
+	if p.consume("select(") {
+		cond, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return func(v interface{}) []interface{} {
+			if cond(v) {
+				return []interface{}{v}
+			}
+			return nil
+		}, nil
+	}
+	return p.parsePath()
+}
+
+// parsePath parses a path such as .items[].name into a step yielding every
+// value it reaches.
+func (p *queryParser) parsePath() (queryStep, error) {
+	if !p.peek(".") {
+		return nil, p.errorf("expected path")
+	}
+
+	var steps []queryStep
+	for !p.eof() {
+		switch {
+		case p.src[p.pos] == '.':
+			p.pos++
+			if p.eof() || strings.ContainsRune(" |)[=!<>", rune(p.src[p.pos])) {
+				continue
+			}
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, memberStep(key))
+		case p.src[p.pos] == '[':
+			step, err := p.parseIndex()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		default:
+			return chainSteps(steps), nil
+		}
+	}
+	return chainSteps(steps), nil
+}
+
+func (p *queryParser) parseKey() (string, error) {
+	if p.src[p.pos] == '"' {
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return "", p.errorf("unterminated key")
+		}
+		key, err := strconv.Unquote(p.src[p.pos : end+1])
+		p.pos = end + 1
+		return key, err
+	}
+
+	start := p.pos
+	for !p.eof() && isKeyChar(p.src[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected key")
+	}
+	return p.src[start:p.pos], nil
+}
+
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '$' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *queryParser) parseIndex() (queryStep, error) {
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 {
+		return nil, p.errorf("expected ]")
+	}
+	inner := strings.TrimSpace(p.src[p.pos : p.pos+end])
+	p.pos += end + 1
+
+	if inner == "" {
+		return iterateStep, nil
+	}
+	i, err := strconv.Atoi(inner)
+	if err != nil {
+		return nil, p.errorf("invalid index %q", inner)
+	}
+	return indexStep(i), nil
+}
+
+// parseCondition parses comparisons joined by and/or, with and binding
+// tighter than or.
+func (p *queryParser) parseCondition() (func(v interface{}) bool, error) {
+	var alternatives []func(v interface{}) bool
+	var all []func(v interface{}) bool
+
+	for {
+		cmp, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, cmp)
+
+		switch {
+		case p.consume("and "):
+		case p.consume("or "):
+			alternatives = append(alternatives, allOf(all))
+			all = nil
+		default:
+			alternatives = append(alternatives, allOf(all))
+			return func(v interface{}) bool {
+				for _, c := range alternatives {
+					if c(v) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		}
+	}
+}
+
+func allOf(conds []func(v interface{}) bool) func(v interface{}) bool {
+	return func(v interface{}) bool {
+		for _, c := range conds {
+			if !c(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (p *queryParser) parseComparison() (func(v interface{}) bool, error) {
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	op := ""
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return func(v interface{}) bool {
+			for _, r := range path(v) {
+				if r != nil && r != false {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	p.skipSpace()
+	dec := json.NewDecoder(strings.NewReader(p.src[p.pos:]))
+	var literal interface{}
+	if err := dec.Decode(&literal); err != nil {
+		return nil, p.errorf("invalid literal: %v", err)
+	}
+	p.pos += int(dec.InputOffset())
+
+	return func(v interface{}) bool {
+		for _, r := range path(v) {
+			if compare(r, op, literal) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func compare(a interface{}, op string, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch op {
+			case "==":
+				return x == y
+			case "!=":
+				return x != y
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			switch op {
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b)
+	case "!=":
+		return !reflect.DeepEqual(a, b)
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func chainSteps(steps []queryStep) queryStep {
+	return func(v interface{}) []interface{} {
+		results := []interface{}{v}
+		for _, step := range steps {
+			var next []interface{}
+			for _, r := range results {
+				next = append(next, step(r)...)
+			}
+			results = next
+		}
+		return results
+	}
+}
+
+func memberStep(key string) queryStep {
+	return func(v interface{}) []interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			return []interface{}{v[key]}
+		case *object:
+			value, _ := v.get(key)
+			return []interface{}{value}
+		}
+		return []interface{}{nil}
+	}
+}
+
+func indexStep(i int) queryStep {
+	return func(v interface{}) []interface{} {
+		a, ok := v.([]interface{})
+		j := i
+		if j < 0 {
+			j += len(a)
+		}
+		if !ok || j < 0 || j >= len(a) {
+			return []interface{}{nil}
+		}
+		return []interface{}{a[j]}
+	}
+}
+
+func iterateStep(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			values = append(values, value)
+		}
+		return values
+	case *object:
+		return v.values
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package colorjson
+
+import (
+	"bufio"
+	"net/url"
+	"strings"
+)
+
+// decodeQueryString returns the parameters of s, in order, if it is a
+// query string or form-encoded body such as "a=1&b=x%20y", or a URL with
+// one. Parameters given more than once become arrays.
+func decodeQueryString(s string) (*object, bool) {
+	if strings.ContainsAny(s, " \t\r\n") {
+		return nil, false
+	}
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		s = s[i+1:]
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = s[:i]
+	}
+
+	params := &object{}
+	values := false
+	for _, pair := range strings.FieldsFunc(s, func(r rune) bool { return r == '&' || r == ';' }) {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 || strings.IndexByte(pair[i+1:], '=') >= 0 {
+			return nil, false
+		}
+		key, err := url.QueryUnescape(pair[:i])
+		if err != nil {
+			return nil, false
+		}
+		value, err := url.QueryUnescape(pair[i+1:])
+		if err != nil {
+			return nil, false
+		}
+		values = values || value != ""
+
+		j := keyRank(params.keys, key)
+		if j == len(params.keys) {
+			params.keys = append(params.keys, key)
+			params.values = append(params.values, value)
+			continue
+		}
+		list, ok := params.values[j].([]interface{})
+		if !ok {
+			list = []interface{}{params.values[j]}
+		}
+		params.values[j] = append(list, value)
+	}
+	return params, values
+}
+
+// writeQueryStringPreview follows a string value with its parameters if it
+// is a query string.
+func (f *Formatter) writeQueryStringPreview(s string, w *bufio.Writer) (int, error) {
+	if !f.QueryStringPreview {
+		return 0, nil
+	}
+	params, ok := decodeQueryString(s)
+	if !ok {
+		return 0, nil
+	}
+	return w.WriteString(" " + f.sprintColor(f.CommentColor, "/* query:") + " " + escapeComment(f.inlineJSON(params)) + " " + f.sprintColor(f.CommentColor, "*/"))
+}
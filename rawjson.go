@@ -0,0 +1,184 @@
+package colorjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// rawString is a JSON string as it appeared in the input, quotes and
+// escapes included.
+type rawString string
+
+// rawParser builds a document from JSON text while keeping the text of
+// strings and numbers, so they can be written back unchanged.
+type rawParser struct {
+	data []byte
+	pos  int
+}
+
+// parseRaw parses a single JSON document. Objects keep their key order.
+func parseRaw(data []byte) (interface{}, error) {
+	p := &rawParser{data: data}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos != len(p.data) {
+		return nil, p.errorf("unexpected data after the document")
+	}
+	return v, nil
+}
+
+func (p *rawParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("colorjson: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *rawParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *rawParser) value() (interface{}, error) {
+	p.skipSpace()
+	if p.pos == len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		return p.str()
+	case c == '-' || c >= '0' && c <= '9':
+		return p.number(), nil
+	case p.literal("true"):
+		return true, nil
+	case p.literal("false"):
+		return false, nil
+	case p.literal("null"):
+		return nil, nil
+	}
+	return nil, p.errorf("unexpected %q", p.data[p.pos])
+}
+
+func (p *rawParser) literal(s string) bool {
+	if bytes.HasPrefix(p.data[p.pos:], []byte(s)) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// expect consumes c after optional white space.
+func (p *rawParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos == len(p.data) || p.data[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// more reports whether another member or element follows, consuming the
+// closing delimiter if not.
+func (p *rawParser) more(end byte, first bool) (bool, error) {
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == end {
+		p.pos++
+		return false, nil
+	}
+	if first {
+		return true, nil
+	}
+	return true, p.expect(',')
+}
+
+func (p *rawParser) object() (interface{}, error) {
+	p.pos++
+	o := &object{}
+	for first := true; ; first = false {
+		more, err := p.more('}', first)
+		if err != nil || !more {
+			return o, err
+		}
+
+		p.skipSpace()
+		if p.pos == len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected a key")
+		}
+		raw, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal([]byte(raw), &key); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		o.keys = append(o.keys, key)
+		o.values = append(o.values, value)
+		o.raw = append(o.raw, raw)
+	}
+}
+
+func (p *rawParser) array() (interface{}, error) {
+	p.pos++
+	a := []interface{}{}
+	for first := true; ; first = false {
+		more, err := p.more(']', first)
+		if err != nil || !more {
+			return a, err
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, value)
+	}
+}
+
+func (p *rawParser) str() (rawString, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			return rawString(p.data[start:p.pos]), nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *rawParser) number() json.Number {
+	start := p.pos
+	for p.pos < len(p.data) && isNumberByte(p.data[p.pos]) {
+		p.pos++
+	}
+	return json.Number(p.data[start:p.pos])
+}
+
+func isNumberByte(c byte) bool {
+	return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,186 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// isScalar reports whether n is written without nesting.
+func (n node) isScalar() bool {
+	if n.filtered {
+		return true
+	}
+	if n.fast {
+		switch n.plain.(type) {
+		case nil, string, bool, float64, int, json.Number, rawString, errorText:
+			return true
+		case *object, map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+
+	v := n.reflect()
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if _, ok := errorOf(v); ok {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return false
+	}
+	return true
+}
+
+func allScalars(length int, at func(i int) (int, node)) bool {
+	for i := 0; i < length; i++ {
+		if _, el := at(i); !el.isScalar() {
+			return false
+		}
+	}
+	return true
+}
+
+// visibleWidth is the number of columns s takes up on a terminal.
+func visibleWidth(s string) int {
+	var buf bytes.Buffer
+	(&stripWriter{w: &buf}).Write([]byte(s))
+	return utf8.RuneCount(buf.Bytes())
+}
+
+// writeScalarElems writes an array of scalars on one line, or, if that is
+// wider than MaxWidth, with as many elements on each line as fit.
+func (f *Formatter) writeScalarElems(length int, at func(i int) (int, node), w *bufio.Writer, depth int) (int, error) {
+	items := make([]string, length)
+	for i := range items {
+		index, el := at(i)
+
+		var buf bytes.Buffer
+		item := bufio.NewWriter(&buf)
+		f.pushIndex(index)
+		_, err := f.marshalNode(el, item, depth+1)
+		f.pop()
+		if err == nil {
+			err = item.Flush()
+		}
+		if err != nil {
+			return 0, err
+		}
+		items[i] = buf.String()
+		if f.ArrayIndexes != IndexOff {
+			last, _ := at(length - 1)
+			items[i] = f.indexLabel(index, last) + items[i]
+		}
+	}
+
+	d := f.decorations()
+	line := f.punct(d.ArrayOpen) + d.Padding + strings.Join(items, f.punct(f.elementSep())) + d.Padding + f.punct(d.ArrayClose)
+	if f.MaxWidth <= 0 || f.Indent*depth+visibleWidth(line) <= f.MaxWidth {
+		return w.WriteString(line)
+	}
+
+	var wr int
+	n, err := w.WriteString(f.punct(d.ArrayOpen))
+	f.openFold()
+	if err != nil {
+		return wr, err
+	}
+
+	wr += n
+
+	n, err = f.writeObjSep(w)
+	if err != nil {
+		return wr, err
+	}
+
+	wr += n
+
+	sep := f.punct(f.lineSep())
+	indent := f.Indent * (depth + 1)
+	width := 0
+	for i, item := range items {
+		itemWidth := visibleWidth(item) + len(f.lineSep())
+		var prefix string
+		switch {
+		case width == 0:
+			prefix = strings.Repeat(" ", indent)
+			width = indent
+		case width+1+itemWidth > f.MaxWidth:
+			n, err = f.writeObjSep(w)
+			if err != nil {
+				return wr, err
+			}
+
+			wr += n
+			prefix = strings.Repeat(" ", indent)
+			width = indent
+		default:
+			prefix = " "
+			width++
+		}
+		width += itemWidth
+
+		if i < length-1 {
+			item += sep
+		}
+		n, err = w.WriteString(prefix + item)
+		if err != nil {
+			return wr, err
+		}
+
+		wr += n
+	}
+
+	n, err = f.writeObjSep(w)
+	if err != nil {
+		return wr, err
+	}
+
+	wr += n
+
+	n, err = f.writeIndent(w, depth)
+	if err != nil {
+		return wr, err
+	}
+
+	wr += n
+
+	n, err = w.WriteString(f.punct(d.ArrayClose))
+	f.closeFold()
+	if err != nil {
+		return wr, err
+	}
+
+	wr += n
+
+	return wr, nil
+}
+
+// formatFloat formats f, of the given bit size, as encoding/json does: the
+// shortest decimal that round-trips at that size, with an exponent below
+// 1e-6 and from 1e21 on.
+func formatFloat(f float64, bits int) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return string(b)
+}
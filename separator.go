@@ -0,0 +1,67 @@
+package colorjson
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Separator selects what a Stream writes between documents.
+type Separator int
+
+const (
+	// SeparatorNone writes each document on its own line.
+	SeparatorNone Separator = iota
+	// SeparatorBlank writes a blank line between documents.
+	SeparatorBlank
+	// SeparatorRule writes a horizontal rule between documents.
+	SeparatorRule
+	// SeparatorCounter writes the number of each document before it:
+	// "── #3 ──".
+	SeparatorCounter
+	// SeparatorRS writes an RS character (0x1E) before each document, as
+	// in application/json-seq (RFC 7464).
+	SeparatorRS
+)
+
+const (
+	recordSeparator = 0x1e
+	ruleWidth       = 40
+)
+
+// separator returns what is written before the record numbered n,
+// counting from 1.
+func (s *Stream) separator(n int) string {
+	switch s.Separator {
+	case SeparatorBlank:
+		if n > 1 {
+			return "\n"
+		}
+	case SeparatorRule:
+		if n > 1 {
+			return s.sprintColor(s.CommentColor, strings.Repeat("─", ruleWidth)) + "\n"
+		}
+	case SeparatorCounter:
+		return s.sprintColor(s.CommentColor, headerRule+" #"+strconv.Itoa(n)+" "+headerRule) + "\n"
+	case SeparatorRS:
+		return string(rune(recordSeparator))
+	}
+	return ""
+}
+
+// rsReader reads a stream with RS characters, as in application/json-seq,
+// replaced by line feeds, which the decoder skips. JSON can't contain them
+// unescaped, so other input reads the same.
+type rsReader struct {
+	r io.Reader
+}
+
+func (r rsReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i, c := range p[:n] {
+		if c == recordSeparator {
+			p[i] = '\n'
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,101 @@
+package colorjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// maxSSELine is the longest line CopySSE accepts.
+const maxSSELine = 1 << 20
+
+// sseEvent is a Server-Sent Event being read.
+type sseEvent struct {
+	name string
+	id   string
+	data []string
+}
+
+// CopySSE colorizes a Server-Sent Events stream read from r, such as the
+// output of curl -N. The data of each event is written like a document
+// read by Copy, after a dim header with the event's name and id. Data that
+// isn't JSON, such as "[DONE]", is written as it is.
+func (s *Stream) CopySSE(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxSSELine)
+
+	var event sseEvent
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			if err := s.writeEvent(event); err != nil {
+				return err
+			}
+			event = sseEvent{}
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.name = value
+		case "id":
+			event.id = value
+		case "data":
+			event.data = append(event.data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// The stream may end without a blank line after the last event.
+	return s.writeEvent(event)
+}
+
+// writeEvent writes an event; events without data are skipped.
+func (s *Stream) writeEvent(event sseEvent) error {
+	if len(event.data) == 0 {
+		return nil
+	}
+
+	var header []string
+	if event.name != "" {
+		header = append(header, "event: "+event.name)
+	}
+	if event.id != "" {
+		header = append(header, "id: "+event.id)
+	}
+	if len(header) > 0 {
+		if _, err := s.Buffer.WriteString(s.sprintColor(s.CommentColor, strings.Join(header, " ")) + "\n"); err != nil {
+			return err
+		}
+	}
+
+	data := strings.Join(event.data, "\n")
+	v, ok := decodeSingle(data)
+	if !ok {
+		if _, err := s.Buffer.WriteString(data + "\n"); err != nil {
+			return err
+		}
+		return s.Buffer.Flush()
+	}
+	return s.writeDocument(v)
+}
+
+// decodeSingle decodes data if it is exactly one JSON document.
+func decodeSingle(data string) (interface{}, bool) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, false
+	}
+	return v, true
+}
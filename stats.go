@@ -0,0 +1,23 @@
+package colorjson
+
+// Stats describes what an encode wrote.
+type Stats struct {
+	// Bytes is the size of the output, escape sequences included.
+	Bytes int
+	// Nodes is the number of values visited.
+	Nodes int
+	// MaxDepth is the deepest nesting level reached, 0 for scalars.
+	MaxDepth int
+	// Truncated is the number of strings cut at StringMaxLength.
+	Truncated int
+	// Elided is the number of members and elements left out or replaced by
+	// markers because of filters.
+	Elided int
+}
+
+// EncodeStats is Encode, returning statistics about the output.
+func (f *Formatter) EncodeStats(jsonObj interface{}, opts ...Option) (Stats, error) {
+	f = f.withOptions(opts)
+	err := f.Encode(jsonObj)
+	return f.stats, err
+}
@@ -0,0 +1,179 @@
+package colorjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/gookit/color"
+)
+
+const gutterMark = "┃ "
+
+// DefaultLevelColors maps common log level names, lower case, to the color
+// records of that level are tinted with.
+var DefaultLevelColors = map[string]color.PrinterFace{
+	"trace":    color.C256(244),
+	"debug":    color.C256(244),
+	"info":     color.FgGreen,
+	"notice":   color.FgCyan,
+	"warn":     color.FgYellow,
+	"warning":  color.FgYellow,
+	"error":    color.FgRed,
+	"err":      color.FgRed,
+	"critical": color.FgLightRed,
+	"fatal":    color.FgLightRed,
+	"panic":    color.FgLightRed,
+}
+
+// numericLevels maps pino/bunyan style numeric levels to names.
+var numericLevels = map[string]string{
+	"10": "trace",
+	"20": "debug",
+	"30": "info",
+	"40": "warn",
+	"50": "error",
+	"60": "fatal",
+}
+
+// Stream colorizes a sequence of JSON documents, such as NDJSON logs,
+// writing each one followed by a newline. Object keys keep the order they
+// have in the input.
+type Stream struct {
+	*Formatter
+
+	// LevelKey names the field holding a record's severity, e.g. "level".
+	// Records are tinted by the color LevelColors has for its value.
+	LevelKey    string
+	LevelColors map[string]color.PrinterFace
+	// TintRecords tints the punctuation of the whole record instead of
+	// marking it with a colored gutter.
+	TintRecords bool
+	// Query, if set, selects what is written from each document.
+	Query *Query
+	// Header, if set, is a template for a line written before each record,
+	// in which {name} is replaced by the record's name field and
+	// {http.status} by a nested one: "{time} service={service}".
+	Header string
+	// Separator is written between documents.
+	Separator Separator
+
+	records int
+	// levelOf, set by presets, names the level of a record in place of
+	// LevelKey.
+	levelOf func(o *object) string
+	// prepare, set by presets, adjusts the formatter of a record to it.
+	prepare func(f *Formatter, v interface{})
+	// rpcMethods holds the methods of the JSON-RPC requests awaiting a
+	// response by direction and id, guarded by rpcMu.
+	rpcMu      sync.Mutex
+	rpcMethods map[string]string
+}
+
+// NewStream returns a Stream writing to w with the default formatter.
+func NewStream(w io.Writer) *Stream {
+	return &Stream{
+		Formatter:   NewFormatter(w),
+		LevelKey:    "",
+		LevelColors: DefaultLevelColors,
+		TintRecords: false,
+		Query:       nil,
+		Header:      "",
+		Separator:   SeparatorNone,
+	}
+}
+
+// Copy colorizes every document read from r. RS characters separating
+// documents, as in application/json-seq, are skipped.
+func (s *Stream) Copy(r io.Reader) error {
+	return decodeAll(rsReader{r}, s.writeDocument)
+}
+
+// writeDocument writes v, or what Query selects from it.
+func (s *Stream) writeDocument(v interface{}) error {
+	if s.Query == nil {
+		return s.writeRecord(v)
+	}
+
+	results, err := s.Query.Run(v)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := s.writeRecord(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stream) writeRecord(v interface{}) error {
+	tint := s.levelColor(v)
+
+	var buf bytes.Buffer
+	f := s.Formatter.clone(&buf)
+	f.TerminateWithNewline = false // the gutter goes before it
+	if s.prepare != nil {
+		s.prepare(f, v)
+	}
+	if tint != nil && s.TintRecords {
+		f.BackColor = tint
+	}
+	if err := f.encode(node{plain: v, fast: true}); err != nil {
+		return err
+	}
+
+	out := buf.String()
+	if tint != nil && !s.TintRecords {
+		gutter := f.sprintColor(tint, gutterMark)
+		out = gutter + strings.ReplaceAll(out, "\n", "\n"+gutter)
+	}
+	if s.Header != "" {
+		headerColor := f.CommentColor
+		if tint != nil {
+			headerColor = tint
+		}
+		out = f.sprintColor(headerColor, s.recordHeader(v)) + "\n" + out
+	}
+
+	s.records++
+	out = s.separator(s.records) + out
+
+	if s.AtomicWrites {
+		return s.writeAtomic([]byte(out + "\n"))
+	}
+	if _, err := s.Buffer.WriteString(out + "\n"); err != nil {
+		return err
+	}
+	return s.Buffer.Flush()
+}
+
+// levelColor returns the tint for a record, or nil if it has none.
+func (s *Stream) levelColor(v interface{}) color.PrinterFace {
+	o, ok := v.(*object)
+	if !ok {
+		return nil
+	}
+	if s.levelOf != nil {
+		return s.LevelColors[s.levelOf(o)]
+	}
+	if s.LevelKey == "" {
+		return nil
+	}
+
+	level, ok := o.get(s.LevelKey)
+	if !ok {
+		return nil
+	}
+
+	var name string
+	switch level := level.(type) {
+	case string:
+		name = strings.ToLower(level)
+	case json.Number:
+		name = numericLevels[level.String()]
+	}
+	return s.LevelColors[name]
+}
@@ -0,0 +1,84 @@
+package colorjson
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// Style is a color with a background and text attributes. It can be used
+// wherever the formatter takes a color:
+//
+//	f.KeyColor = colorjson.Style{Color: color.FgBlue, Bold: true}
+//	f.ErrorColor = colorjson.Style{Color: color.FgWhite, Background: color.BgRed}
+type Style struct {
+	Color color.PrinterFace
+	// Background must be a background color, such as color.BgRed or
+	// color.C256(52, true).
+	Background    color.PrinterFace
+	Bold          bool
+	Faint         bool
+	Italic        bool
+	Underline     bool
+	Strikethrough bool
+}
+
+// String returns the SGR parameters of s.
+func (s Style) String() string {
+	var codes []string
+	for _, c := range []color.PrinterFace{s.Color, s.Background} {
+		if c != nil && c.String() != "" {
+			codes = append(codes, c.String())
+		}
+	}
+
+	attrs := []struct {
+		set  bool
+		code color.Color
+	}{
+		{s.Bold, color.OpBold},
+		{s.Faint, color.OpFuzzy},
+		{s.Italic, color.OpItalic},
+		{s.Underline, color.OpUnderscore},
+		{s.Strikethrough, color.OpStrikethrough},
+	}
+	for _, attr := range attrs {
+		if attr.set {
+			codes = append(codes, attr.code.String())
+		}
+	}
+	return strings.Join(codes, ";")
+}
+
+func (s Style) Sprint(a ...interface{}) string {
+	return color.RenderCode(s.String(), a...)
+}
+
+func (s Style) Sprintf(format string, a ...interface{}) string {
+	return color.RenderCode(s.String(), fmt.Sprintf(format, a...))
+}
+
+func (s Style) Print(a ...interface{}) {
+	fmt.Print(s.Sprint(a...))
+}
+
+func (s Style) Printf(format string, a ...interface{}) {
+	fmt.Print(s.Sprintf(format, a...))
+}
+
+func (s Style) Println(a ...interface{}) {
+	fmt.Println(s.Sprint(a...))
+}
+
+// punct colors structural characters: brackets, separators and the
+// like.
+func (f *Formatter) punct(s string) string {
+	if f.ColoredElements&ElementPunctuation == 0 {
+		return s
+	}
+	if f.FaintPunctuation {
+		return f.sprintColor(Style{Color: f.BackColor, Faint: true}, s)
+	}
+	return f.sprintColor(f.BackColor, s)
+}
@@ -0,0 +1,85 @@
+package colorjson
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Tee makes f write its colored output to colored and the same output with
+// escape sequences removed to plain, from a single marshaling pass.
+func (f *Formatter) Tee(colored, plain io.Writer) {
+	f.Buffer = bufio.NewWriter(io.MultiWriter(colored, &stripWriter{w: plain}))
+}
+
+// StripANSI returns s without the escape sequences colored output has.
+func StripANSI(s string) string {
+	var buf bytes.Buffer
+	(&stripWriter{w: &buf}).Write([]byte(s))
+	return buf.String()
+}
+
+// stripState is where a stripWriter is within an escape sequence.
+type stripState int
+
+const (
+	stripText stripState = iota
+	stripEscape
+	stripCSI
+	stripString
+	stripStringEscape
+)
+
+// stripWriter removes ANSI escape sequences (CSI, OSC and the other string
+// sequences such as kitty's APC) from what it writes to w. Sequences may be
+// split across writes.
+type stripWriter struct {
+	w     io.Writer
+	state stripState
+	buf   []byte
+}
+
+func (s *stripWriter) Write(p []byte) (int, error) {
+	s.buf = s.buf[:0]
+	for _, b := range p {
+		switch s.state {
+		case stripText:
+			if b == 0x1b {
+				s.state = stripEscape
+			} else {
+				s.buf = append(s.buf, b)
+			}
+		case stripEscape:
+			switch b {
+			case '[':
+				s.state = stripCSI
+			case ']', '_', 'P', '^', 'X':
+				s.state = stripString
+			default:
+				s.state = stripText
+			}
+		case stripCSI:
+			if b >= 0x40 && b <= 0x7e {
+				s.state = stripText
+			}
+		case stripString:
+			switch b {
+			case '\a':
+				s.state = stripText
+			case 0x1b:
+				s.state = stripStringEscape
+			}
+		case stripStringEscape:
+			if b == '\\' {
+				s.state = stripText
+			} else {
+				s.state = stripString
+			}
+		}
+	}
+
+	if _, err := s.w.Write(s.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
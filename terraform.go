@@ -0,0 +1,105 @@
+package colorjson
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// TerraformActionColors maps the action of a Terraform resource change to
+// its color. Replacements, deleting and creating a resource, are
+// "replace".
+var TerraformActionColors = map[string]color.PrinterFace{
+	"create":  color.FgGreen,
+	"delete":  color.FgRed,
+	"update":  color.FgYellow,
+	"replace": color.FgMagenta,
+	"read":    color.FgCyan,
+	"no-op":   color.C256(244),
+}
+
+// Terraform sets s up for plans from terraform show -json: the address and
+// actions of each resource change are colored by what is done to it, and
+// the attributes an update leaves unchanged are collapsed, so only what
+// changes stands out.
+func Terraform(s *Stream) {
+	s.KeyOrder = []string{"address", "mode", "type", "name", "change", "actions", "before", "after"}
+	s.prepare = func(f *Formatter, v interface{}) {
+		plan, ok := v.(*object)
+		if !ok {
+			return
+		}
+
+		actions := map[string]color.PrinterFace{}
+		unchanged := map[string]bool{}
+		for _, key := range []string{"resource_changes", "resource_drift"} {
+			changes, _ := plan.get(key)
+			list, _ := changes.([]interface{})
+			for i, rc := range list {
+				prefix := rootPath + "." + key + "[" + strconv.Itoa(i) + "]"
+				rc, _ := rc.(*object)
+				if rc == nil {
+					continue
+				}
+				change, _ := rc.get("change")
+				if change, ok := change.(*object); ok {
+					actions[prefix] = TerraformActionColors[terraformAction(change)]
+					unchangedAttributes(change, prefix+".change", unchanged)
+				}
+			}
+		}
+
+		filter := f.FilterFunc
+		f.FilterFunc = func(path string, v interface{}) bool {
+			return !unchanged[path] && (filter == nil || filter(path, v))
+		}
+		f.ShowFiltered = true
+		valueColor := f.ValueColor
+		f.ValueColor = func(path, value string) color.PrinterFace {
+			if i := strings.Index(path, "]"); i >= 0 {
+				prefix, rest := path[:i+1], path[i+1:]
+				if c := actions[prefix]; c != nil && (rest == ".address" || strings.HasPrefix(rest, ".change.actions[")) {
+					return c
+				}
+			}
+			if valueColor != nil {
+				return valueColor(path, value)
+			}
+			return nil
+		}
+	}
+}
+
+// terraformAction returns the action of a resource change.
+func terraformAction(change *object) string {
+	actions, _ := change.get("actions")
+	list, _ := actions.([]interface{})
+	switch len(list) {
+	case 1:
+		action, _ := list[0].(string)
+		return action
+	case 2:
+		return "replace"
+	}
+	return ""
+}
+
+// unchangedAttributes adds the paths of the attributes that are the same
+// before and after change to paths.
+func unchangedAttributes(change *object, prefix string, paths map[string]bool) {
+	before, _ := change.get("before")
+	after, _ := change.get("after")
+	b, _ := before.(*object)
+	a, _ := after.(*object)
+	if a == nil || b == nil {
+		return
+	}
+	for i, key := range b.keys {
+		if value, ok := a.get(key); ok && reflect.DeepEqual(b.values[i], value) {
+			paths[prefix+".before."+key] = true
+			paths[prefix+".after."+key] = true
+		}
+	}
+}
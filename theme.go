@@ -0,0 +1,213 @@
+package colorjson
+
+import "github.com/gookit/color"
+
+// Theme is a set of colors for the parts of a document.
+type Theme struct {
+	Back   color.PrinterFace
+	Key    color.PrinterFace
+	String color.PrinterFace
+	Bool   color.PrinterFace
+	Number color.PrinterFace
+	Null   color.PrinterFace
+	Error  color.PrinterFace
+}
+
+// Themes holds the built-in themes by name.
+var Themes = map[string]Theme{
+	"default": {
+		Back:   color.FgWhite,
+		Key:    color.C256(250),
+		String: color.FgGreen,
+		Bool:   color.FgYellow,
+		Number: color.FgCyan,
+		Null:   color.FgMagenta,
+		Error:  color.FgRed,
+	},
+	"light": {
+		Back:   color.FgBlack,
+		Key:    color.C256(238),
+		String: color.C256(28),
+		Bool:   color.C256(130),
+		Number: color.C256(25),
+		Null:   color.FgMagenta,
+		Error:  color.FgRed,
+	},
+	"jq": {
+		Back:   color.FgWhite,
+		Key:    color.New(color.FgBlue, color.OpBold),
+		String: color.FgGreen,
+		Bool:   color.FgWhite,
+		Number: color.FgWhite,
+		Null:   color.FgDarkGray,
+		Error:  color.FgRed,
+	},
+	"vivid": {
+		Back:   color.FgWhite,
+		Key:    Style{Color: color.FgBlue, Bold: true},
+		String: color.FgGreen,
+		Bool:   color.FgYellow,
+		Number: color.FgCyan,
+		Null:   Style{Color: color.FgMagenta, Italic: true},
+		Error:  Style{Color: color.FgWhite, Background: color.BgRed, Bold: true},
+	},
+	"vivid-light": {
+		Back:   color.FgBlack,
+		Key:    Style{Color: color.FgBlue, Bold: true},
+		String: color.C256(28),
+		Bool:   color.C256(130),
+		Number: color.C256(25),
+		Null:   Style{Color: color.FgMagenta, Italic: true},
+		Error:  Style{Color: color.FgWhite, Background: color.BgRed, Bold: true},
+	},
+	"jq-light": {
+		Back:   color.FgBlack,
+		Key:    color.New(color.FgBlue, color.OpBold),
+		String: color.C256(28),
+		Bool:   color.FgBlack,
+		Number: color.FgBlack,
+		Null:   color.C256(244),
+		Error:  color.FgRed,
+	},
+	"monokai": {
+		Back:   color.RGB(248, 248, 242),
+		Key:    color.RGB(249, 38, 114),
+		String: color.RGB(230, 219, 116),
+		Bool:   color.RGB(174, 129, 255),
+		Number: color.RGB(174, 129, 255),
+		Null:   color.RGB(102, 217, 239),
+		Error:  color.RGB(249, 38, 114),
+	},
+	"monokai-light": {
+		Back:   color.RGB(45, 42, 46),
+		Key:    color.RGB(214, 31, 104),
+		String: color.RGB(130, 110, 0),
+		Bool:   color.RGB(115, 65, 200),
+		Number: color.RGB(115, 65, 200),
+		Null:   color.RGB(20, 135, 165),
+		Error:  color.RGB(214, 31, 104),
+	},
+	"solarized": {
+		Back:   color.RGB(131, 148, 150),
+		Key:    color.RGB(38, 139, 210),
+		String: color.RGB(42, 161, 152),
+		Bool:   color.RGB(181, 137, 0),
+		Number: color.RGB(211, 54, 130),
+		Null:   color.RGB(108, 113, 196),
+		Error:  color.RGB(220, 50, 47),
+	},
+	"solarized-light": {
+		Back:   color.RGB(101, 123, 131),
+		Key:    color.RGB(38, 139, 210),
+		String: color.RGB(42, 161, 152),
+		Bool:   color.RGB(181, 137, 0),
+		Number: color.RGB(211, 54, 130),
+		Null:   color.RGB(108, 113, 196),
+		Error:  color.RGB(220, 50, 47),
+	},
+	// minimal only colors keys, for screen sharing and projectors. Style{}
+	// leaves text in the terminal's default color.
+	"minimal": {
+		Back:   Style{},
+		Key:    color.FgCyan,
+		String: Style{},
+		Bool:   Style{},
+		Number: Style{},
+		Null:   Style{},
+		Error:  Style{Bold: true},
+	},
+	"minimal-light": {
+		Back:   Style{},
+		Key:    color.FgBlue,
+		String: Style{},
+		Bool:   Style{},
+		Number: Style{},
+		Null:   Style{},
+		Error:  Style{Bold: true},
+	},
+	// grayscale tells elements apart by intensity alone.
+	"grayscale": {
+		Back:   color.C256(245),
+		Key:    Style{Color: color.C256(255), Bold: true},
+		String: color.C256(250),
+		Bool:   color.C256(253),
+		Number: color.C256(253),
+		Null:   Style{Color: color.C256(242), Italic: true},
+		Error:  Style{Color: color.C256(255), Underline: true},
+	},
+	"grayscale-light": {
+		Back:   color.C256(243),
+		Key:    Style{Color: color.C256(232), Bold: true},
+		String: color.C256(238),
+		Bool:   color.C256(235),
+		Number: color.C256(235),
+		Null:   Style{Color: color.C256(244), Italic: true},
+		Error:  Style{Color: color.C256(232), Underline: true},
+	},
+	// monochrome uses text attributes only, for terminals and pagers where
+	// color is unreliable. It suits any background.
+	"monochrome": {
+		Back:   Style{Faint: true},
+		Key:    Style{Bold: true},
+		String: Style{},
+		Bool:   Style{Underline: true},
+		Number: Style{},
+		Null:   Style{Faint: true},
+		Error:  Style{Bold: true, Underline: true},
+	},
+}
+
+// LightVariants pairs themes made for dark backgrounds with their
+// counterparts for light ones.
+var LightVariants = map[string]string{
+	"default":   "light",
+	"jq":        "jq-light",
+	"vivid":     "vivid-light",
+	"monokai":   "monokai-light",
+	"solarized": "solarized-light",
+	"minimal":   "minimal-light",
+	"grayscale": "grayscale-light",
+}
+
+// ThemeFor returns the theme called name in the variant for background b.
+// With BackgroundUnknown the theme is returned as it is. On terminals with
+// only the basic colors, the theme's entry in BasicThemes is used.
+func ThemeFor(name string, b Background) (Theme, bool) {
+	switch b {
+	case BackgroundLight:
+		if light, ok := LightVariants[name]; ok {
+			name = light
+		}
+	case BackgroundDark:
+		for dark, light := range LightVariants {
+			if light == name {
+				name = dark
+				break
+			}
+		}
+	}
+
+	if basicColors() {
+		if t, ok := BasicThemes[name]; ok {
+			return t, true
+		}
+	}
+	t, ok := Themes[name]
+	return t, ok
+}
+
+// SetTheme colors f with t. Colors t leaves nil are kept.
+func (f *Formatter) SetTheme(t Theme) {
+	set := func(dst *color.PrinterFace, c color.PrinterFace) {
+		if c != nil {
+			*dst = c
+		}
+	}
+	set(&f.BackColor, t.Back)
+	set(&f.KeyColor, t.Key)
+	set(&f.StringColor, t.String)
+	set(&f.BoolColor, t.Bool)
+	set(&f.NumberColor, t.Number)
+	set(&f.NullColor, t.Null)
+	set(&f.ErrorColor, t.Error)
+}
@@ -0,0 +1,336 @@
+// Package tree holds the document model shared by the interactive viewers:
+// a tree of JSON nodes with fold state, the visible lines, cursor and search.
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the JSON type of a node.
+type Kind int
+
+const (
+	Scalar Kind = iota
+	Object
+	Array
+)
+
+// Node is a value in the document.
+type Node struct {
+	Key      string
+	InArray  bool
+	Path     string
+	Kind     Kind
+	Value    interface{}
+	Children []*Node
+	Parent   *Node
+	Folded   bool
+}
+
+// Line is a single row of the view: a scalar, the opening line of a
+// container, or its closing bracket.
+type Line struct {
+	Node    *Node
+	Depth   int
+	Closing bool
+	Last    bool
+}
+
+// Class tells renderers how to color a token.
+type Class int
+
+const (
+	Punct Class = iota
+	Key
+	Value
+	Count
+)
+
+// Token is a piece of a rendered line. Value tokens carry the scalar they
+// show so renderers can format it themselves.
+type Token struct {
+	Class Class
+	Text  string
+	Value interface{}
+}
+
+// Doc is a document with its cursor, scroll and search state.
+type Doc struct {
+	Root   *Node
+	Lines  []Line
+	Cursor int
+	Offset int
+	Height int
+
+	Query   string
+	Matches []*Node
+	Match   int
+}
+
+// New builds a document from v. Values that aren't made of
+// map[string]interface{} and []interface{} are converted through
+// encoding/json first.
+func New(v interface{}) (*Doc, error) {
+	v, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Doc{Root: build(v, "", false, "$", nil), Height: 24}
+	d.Relayout()
+	return d, nil
+}
+
+func toGeneric(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, bool, float64, string, json.Number, map[string]interface{}, []interface{}:
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
+
+func build(v interface{}, key string, inArray bool, path string, parent *Node) *Node {
+	n := &Node{Key: key, InArray: inArray, Path: path, Value: v, Parent: parent}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n.Kind = Object
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n.Children = append(n.Children, build(v[k], k, false, path+"."+k, n))
+		}
+	case []interface{}:
+		n.Kind = Array
+		for i, elem := range v {
+			n.Children = append(n.Children, build(elem, "", true, path+"["+strconv.Itoa(i)+"]", n))
+		}
+	}
+	return n
+}
+
+// Selected returns the node under the cursor.
+func (d *Doc) Selected() *Node {
+	return d.Lines[d.Cursor].Node
+}
+
+// Path returns the path of the selected line, such as "$.items[3].name".
+func (d *Doc) Path() string {
+	return d.Selected().Path
+}
+
+// MoveTo moves the cursor to line i, clamped to the document.
+func (d *Doc) MoveTo(i int) {
+	if i >= len(d.Lines) {
+		i = len(d.Lines) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	d.Cursor = i
+	d.scroll()
+}
+
+// PageSize is the number of document lines that fit above the status bar.
+func (d *Doc) PageSize() int {
+	if d.Height <= 2 {
+		return 1
+	}
+	return d.Height - 1
+}
+
+// SetHeight updates the height of the view, status bar included.
+func (d *Doc) SetHeight(height int) {
+	d.Height = height
+	d.scroll()
+}
+
+func (d *Doc) scroll() {
+	if d.Cursor < d.Offset {
+		d.Offset = d.Cursor
+	}
+	if d.Cursor >= d.Offset+d.PageSize() {
+		d.Offset = d.Cursor - d.PageSize() + 1
+	}
+}
+
+// Visible returns the lines inside the scroll window.
+func (d *Doc) Visible() []Line {
+	end := d.Offset + d.PageSize()
+	if end > len(d.Lines) {
+		end = len(d.Lines)
+	}
+	return d.Lines[d.Offset:end]
+}
+
+// Toggle folds or unfolds the selected container.
+func (d *Doc) Toggle() {
+	if n := d.Selected(); n.Kind != Scalar {
+		d.SetFolded(n, !n.Folded)
+	}
+}
+
+// Fold folds the selected container, or its parent if there is nothing to
+// fold.
+func (d *Doc) Fold() {
+	n := d.Selected()
+	if n.Kind != Scalar && !n.Folded {
+		d.SetFolded(n, true)
+	} else if n.Parent != nil {
+		d.SetFolded(n.Parent, true)
+	}
+}
+
+// Unfold unfolds the selected container.
+func (d *Doc) Unfold() {
+	if n := d.Selected(); n.Kind != Scalar {
+		d.SetFolded(n, false)
+	}
+}
+
+// SetFolded changes the fold state of n and puts the cursor on it.
+func (d *Doc) SetFolded(n *Node, folded bool) {
+	n.Folded = folded
+	d.Relayout()
+	d.moveToNode(n)
+}
+
+func (d *Doc) moveToNode(n *Node) {
+	for i, l := range d.Lines {
+		if l.Node == n && !l.Closing {
+			d.MoveTo(i)
+			return
+		}
+	}
+}
+
+// Search finds every node whose key or scalar value contains query, case
+// insensitively, and jumps to the first one.
+func (d *Doc) Search(query string) {
+	d.Query = query
+	d.Matches = nil
+	if query != "" {
+		d.search(d.Root, strings.ToLower(query))
+	}
+	d.JumpToMatch(0)
+}
+
+func (d *Doc) search(n *Node, query string) {
+	text := strings.ToLower(n.Key)
+	if n.Kind == Scalar {
+		text += " " + strings.ToLower(fmt.Sprint(n.Value))
+	}
+	if strings.Contains(text, query) {
+		d.Matches = append(d.Matches, n)
+	}
+	for _, c := range n.Children {
+		d.search(c, query)
+	}
+}
+
+// JumpToMatch moves to search match i, wrapping around and unfolding the
+// containers around it.
+func (d *Doc) JumpToMatch(i int) {
+	if len(d.Matches) == 0 {
+		return
+	}
+	d.Match = (i%len(d.Matches) + len(d.Matches)) % len(d.Matches)
+
+	target := d.Matches[d.Match]
+	for p := target.Parent; p != nil; p = p.Parent {
+		p.Folded = false
+	}
+	d.Relayout()
+	d.moveToNode(target)
+}
+
+// Status describes the selection and search state for a status bar.
+func (d *Doc) Status() string {
+	status := d.Path()
+	if d.Query != "" {
+		if len(d.Matches) == 0 {
+			status += fmt.Sprintf("  [no matches for %q]", d.Query)
+		} else {
+			status += fmt.Sprintf("  [%d/%d %q]", d.Match+1, len(d.Matches), d.Query)
+		}
+	}
+	return status
+}
+
+// Relayout recomputes the visible lines after fold changes.
+func (d *Doc) Relayout() {
+	d.Lines = d.Lines[:0]
+	d.layout(d.Root, 0, true)
+	d.MoveTo(d.Cursor)
+}
+
+func (d *Doc) layout(n *Node, depth int, last bool) {
+	d.Lines = append(d.Lines, Line{Node: n, Depth: depth, Last: last})
+	if n.Kind == Scalar || n.Folded || len(n.Children) == 0 {
+		return
+	}
+	for i, c := range n.Children {
+		d.layout(c, depth+1, i == len(n.Children)-1)
+	}
+	d.Lines = append(d.Lines, Line{Node: n, Depth: depth, Closing: true, Last: last})
+}
+
+// Tokens splits l into the pieces a renderer colors, without indentation.
+func (l Line) Tokens() []Token {
+	n := l.Node
+	var tokens []Token
+
+	if !l.Closing && n.Parent != nil && !n.InArray {
+		tokens = append(tokens, Token{Class: Key, Text: strconv.Quote(n.Key) + ": "})
+	}
+
+	switch {
+	case l.Closing:
+		tokens = append(tokens, Token{Class: Punct, Text: closer(n.Kind)})
+	case n.Kind == Scalar:
+		text, _ := json.Marshal(n.Value)
+		tokens = append(tokens, Token{Class: Value, Text: string(text), Value: n.Value})
+	case len(n.Children) == 0:
+		tokens = append(tokens, Token{Class: Punct, Text: opener(n.Kind) + closer(n.Kind)})
+	case n.Folded:
+		tokens = append(tokens,
+			Token{Class: Punct, Text: opener(n.Kind) + "…" + closer(n.Kind)},
+			Token{Class: Count, Text: fmt.Sprintf(" %d", len(n.Children))})
+	default:
+		return append(tokens, Token{Class: Punct, Text: opener(n.Kind)})
+	}
+
+	if !l.Last {
+		tokens = append(tokens, Token{Class: Punct, Text: ","})
+	}
+	return tokens
+}
+
+func opener(k Kind) string {
+	if k == Array {
+		return "["
+	}
+	return "{"
+}
+
+func closer(k Kind) string {
+	if k == Array {
+		return "]"
+	}
+	return "}"
+}
@@ -0,0 +1,186 @@
+// Package tui provides a Bubble Tea model for browsing JSON documents
+// colored by a colorjson.Formatter.
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gookit/color"
+	"github.com/olebeck/colorjson"
+	"github.com/olebeck/colorjson/tui/internal/tree"
+)
+
+const cursorMark = "▸ "
+const noCursorMark = "  "
+
+// Model shows a JSON document with foldable objects and arrays, incremental
+// search and the path of the selected line in a status bar.
+//
+// Keys: up/down (k/j), pgup/pgdown, home/end (g/G), enter or space toggles
+// a fold, left/right (h/l) fold and unfold, / searches, n/N jump between
+// matches and q quits.
+type Model struct {
+	// Formatter supplies the colors, indentation and string limits.
+	Formatter *colorjson.Formatter
+
+	doc       *tree.Doc
+	searching bool
+	query     string
+}
+
+// New returns a model showing v. Values that aren't made of
+// map[string]interface{} and []interface{} are converted through
+// encoding/json first.
+func New(v interface{}) (Model, error) {
+	doc, err := tree.New(v)
+	if err != nil {
+		return Model{}, err
+	}
+
+	f := colorjson.NewFormatter(nil)
+	f.Indent = 2
+
+	return Model{Formatter: f, doc: doc}, nil
+}
+
+// Init implements tea.Model.
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.doc.SetHeight(msg.Height)
+	case tea.KeyMsg:
+		if m.searching {
+			m.updateSearch(msg)
+			return m, nil
+		}
+		return m.updateKey(msg)
+	}
+	return m, nil
+}
+
+func (m Model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.doc
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		d.MoveTo(d.Cursor - 1)
+	case "down", "j":
+		d.MoveTo(d.Cursor + 1)
+	case "pgup":
+		d.MoveTo(d.Cursor - d.PageSize())
+	case "pgdown":
+		d.MoveTo(d.Cursor + d.PageSize())
+	case "home", "g":
+		d.MoveTo(0)
+	case "end", "G":
+		d.MoveTo(len(d.Lines) - 1)
+	case "enter", " ":
+		d.Toggle()
+	case "left", "h":
+		d.Fold()
+	case "right", "l":
+		d.Unfold()
+	case "/":
+		m.searching = true
+		m.query = ""
+	case "n":
+		d.JumpToMatch(d.Match + 1)
+	case "N":
+		d.JumpToMatch(d.Match - 1)
+	}
+	return m, nil
+}
+
+func (m *Model) updateSearch(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+		return
+	case tea.KeyEsc:
+		m.searching = false
+		m.query = ""
+	case tea.KeyBackspace:
+		if m.query != "" {
+			r := []rune(m.query)
+			m.query = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.query += string(msg.Runes)
+	default:
+		return
+	}
+	m.doc.Search(m.query)
+}
+
+// Path returns the path of the selected line, such as "$.items[3].name".
+func (m Model) Path() string {
+	return m.doc.Path()
+}
+
+// View implements tea.Model.
+func (m Model) View() string {
+	var b strings.Builder
+
+	for i, l := range m.doc.Visible() {
+		if m.doc.Offset+i == m.doc.Cursor {
+			b.WriteString(cursorMark)
+		} else {
+			b.WriteString(noCursorMark)
+		}
+		b.WriteString(m.renderLine(l))
+		b.WriteByte('\n')
+	}
+
+	if m.searching {
+		b.WriteString("/" + m.query)
+	} else {
+		b.WriteString(m.paint(color.New(color.OpReverse), m.doc.Status()))
+	}
+	return b.String()
+}
+
+func (m Model) renderLine(l tree.Line) string {
+	f := m.Formatter
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", f.Indent*l.Depth))
+
+	for _, t := range l.Tokens() {
+		switch t.Class {
+		case tree.Key:
+			b.WriteString(m.paint(f.KeyColor, t.Text))
+		case tree.Value:
+			b.WriteString(m.encode(t.Value))
+		case tree.Count:
+			b.WriteString(m.paint(color.New(color.OpFuzzy), t.Text))
+		default:
+			b.WriteString(m.paint(f.BackColor, t.Text))
+		}
+	}
+	return b.String()
+}
+
+// encode renders a scalar with a copy of the formatter.
+func (m Model) encode(v interface{}) string {
+	var buf bytes.Buffer
+	f := *m.Formatter
+	f.Buffer = bufio.NewWriter(&buf)
+	f.EncodeValue(reflect.ValueOf(v))
+	return buf.String()
+}
+
+func (m Model) paint(c color.PrinterFace, s string) string {
+	if m.Formatter.DisabledColor || c == nil {
+		return s
+	}
+	return c.Sprint(s)
+}
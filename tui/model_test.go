@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func press(m Model, keys ...string) Model {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+func TestModel(t *testing.T) {
+	m, err := New(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "web"},
+			map[string]interface{}{"name": "db"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Formatter.DisabledColor = true
+
+	m = press(m, "down", "down", "enter")
+	if got, want := m.Path(), "$.items[0]"; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+	if view := m.View(); !strings.Contains(view, "{…} 1") {
+		t.Errorf("folded object not shown:\n%s", view)
+	}
+
+	m = press(m, "/", "d", "b", "enter")
+	if got, want := m.Path(), "$.items[1].name"; got != want {
+		t.Errorf("path after search = %q, want %q", got, want)
+	}
+}
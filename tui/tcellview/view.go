@@ -0,0 +1,267 @@
+// Package tcellview provides ColorJSONView, a JSON viewer drawn directly on
+// a tcell screen, for embedding in tview and other tcell based dashboards.
+//
+// The view only needs a drawing area and key events, so it plugs into a
+// tview Box without further glue:
+//
+//	view, _ := tcellview.New(obj)
+//	box := tview.NewBox().SetDrawFunc(func(s tcell.Screen, x, y, w, h int) (int, int, int, int) {
+//		view.Draw(s, x, y, w, h)
+//		return x, y, w, h
+//	})
+//	box.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+//		if view.HandleKey(ev) {
+//			return nil
+//		}
+//		return ev
+//	})
+package tcellview
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gookit/color"
+	"github.com/mattn/go-runewidth"
+	"github.com/olebeck/colorjson"
+	"github.com/olebeck/colorjson/tui/internal/tree"
+)
+
+const cursorMark = "▸ "
+
+// ColorJSONView shows a JSON document themed by a colorjson.Formatter, with
+// scrolling, collapsible objects and arrays and search.
+//
+// Keys: up/down (k/j), pgup/pgdown, home/end, enter toggles a fold,
+// left/right fold and unfold, / searches and n/N jump between matches.
+type ColorJSONView struct {
+	// Formatter supplies the colors, indentation and string limits.
+	Formatter *colorjson.Formatter
+
+	doc       *tree.Doc
+	searching bool
+	query     string
+}
+
+// New returns a view showing v.
+func New(v interface{}) (*ColorJSONView, error) {
+	doc, err := tree.New(v)
+	if err != nil {
+		return nil, err
+	}
+
+	f := colorjson.NewFormatter(nil)
+	f.Indent = 2
+
+	return &ColorJSONView{Formatter: f, doc: doc}, nil
+}
+
+// Path returns the path of the selected line, such as "$.items[3].name".
+func (v *ColorJSONView) Path() string {
+	return v.doc.Path()
+}
+
+// HandleKey applies a key event and reports whether it was used.
+func (v *ColorJSONView) HandleKey(ev *tcell.EventKey) bool {
+	if v.searching {
+		return v.handleSearchKey(ev)
+	}
+
+	d := v.doc
+	switch ev.Key() {
+	case tcell.KeyUp:
+		d.MoveTo(d.Cursor - 1)
+	case tcell.KeyDown:
+		d.MoveTo(d.Cursor + 1)
+	case tcell.KeyPgUp:
+		d.MoveTo(d.Cursor - d.PageSize())
+	case tcell.KeyPgDn:
+		d.MoveTo(d.Cursor + d.PageSize())
+	case tcell.KeyHome:
+		d.MoveTo(0)
+	case tcell.KeyEnd:
+		d.MoveTo(len(d.Lines) - 1)
+	case tcell.KeyEnter:
+		d.Toggle()
+	case tcell.KeyLeft:
+		d.Fold()
+	case tcell.KeyRight:
+		d.Unfold()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'k':
+			d.MoveTo(d.Cursor - 1)
+		case 'j':
+			d.MoveTo(d.Cursor + 1)
+		case ' ':
+			d.Toggle()
+		case '/':
+			v.searching = true
+			v.query = ""
+		case 'n':
+			d.JumpToMatch(d.Match + 1)
+		case 'N':
+			d.JumpToMatch(d.Match - 1)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (v *ColorJSONView) handleSearchKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		v.searching = false
+		return true
+	case tcell.KeyEscape:
+		v.searching = false
+		v.query = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if v.query != "" {
+			r := []rune(v.query)
+			v.query = string(r[:len(r)-1])
+		}
+	case tcell.KeyRune:
+		v.query += string(ev.Rune())
+	default:
+		return false
+	}
+	v.doc.Search(v.query)
+	return true
+}
+
+// Draw renders the view into the given area of screen, with the status bar
+// on its last row.
+func (v *ColorJSONView) Draw(screen tcell.Screen, x, y, width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	v.doc.SetHeight(height)
+
+	f := v.Formatter
+	base := tcell.StyleDefault
+
+	for row, l := range v.doc.Visible() {
+		col := x
+		if v.doc.Offset+row == v.doc.Cursor {
+			col = put(screen, col, y+row, x+width, cursorMark, base.Bold(true))
+		} else {
+			col = put(screen, col, y+row, x+width, strings.Repeat(" ", len([]rune(cursorMark))), base)
+		}
+		col = put(screen, col, y+row, x+width, strings.Repeat(" ", f.Indent*l.Depth), base)
+
+		for _, t := range l.Tokens() {
+			switch t.Class {
+			case tree.Key:
+				col = put(screen, col, y+row, x+width, t.Text, v.style(f.KeyColor))
+			case tree.Value:
+				col = put(screen, col, y+row, x+width, v.scalar(t.Value), v.style(v.valueColor(t.Value)))
+			case tree.Count:
+				col = put(screen, col, y+row, x+width, t.Text, base.Dim(true))
+			default:
+				col = put(screen, col, y+row, x+width, t.Text, v.style(f.BackColor))
+			}
+		}
+	}
+
+	status := v.doc.Status()
+	if v.searching {
+		status = "/" + v.query
+	}
+	put(screen, x, y+height-1, x+width, status, base.Reverse(true))
+}
+
+// put writes s starting at column x, clipped at column max, and returns the
+// column after it.
+func put(screen tcell.Screen, x, y, max int, s string, style tcell.Style) int {
+	for _, r := range s {
+		w := runewidth.RuneWidth(r)
+		if x+w > max {
+			return x
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x += w
+	}
+	return x
+}
+
+// scalar formats a scalar without colors, honoring the formatter's string
+// options.
+func (v *ColorJSONView) scalar(value interface{}) string {
+	var buf bytes.Buffer
+	f := *v.Formatter
+	f.DisabledColor = true
+	f.Buffer = bufio.NewWriter(&buf)
+	f.EncodeValue(reflect.ValueOf(value))
+	return buf.String()
+}
+
+func (v *ColorJSONView) valueColor(value interface{}) color.PrinterFace {
+	f := v.Formatter
+	switch value.(type) {
+	case nil:
+		return f.NullColor
+	case bool:
+		return f.BoolColor
+	case string:
+		return f.StringColor
+	}
+	return f.NumberColor
+}
+
+func (v *ColorJSONView) style(c color.PrinterFace) tcell.Style {
+	if v.Formatter.DisabledColor {
+		return tcell.StyleDefault
+	}
+
+	s, ok := c.(colorjson.Style)
+	if !ok {
+		return tcell.StyleDefault.Foreground(tcellColor(c))
+	}
+
+	style := tcell.StyleDefault.Foreground(tcellColor(s.Color)).
+		Bold(s.Bold).
+		Dim(s.Faint).
+		Italic(s.Italic).
+		Underline(s.Underline).
+		StrikeThrough(s.Strikethrough)
+	if s.Background != nil {
+		style = style.Background(tcellColor(s.Background))
+	}
+	return style
+}
+
+// tcellColor converts a gookit color to its tcell counterpart.
+func tcellColor(c color.PrinterFace) tcell.Color {
+	switch c := c.(type) {
+	case color.Color:
+		switch {
+		case c >= color.FgBlack && c <= color.FgWhite:
+			return tcell.PaletteColor(int(c - color.FgBlack))
+		case c >= color.FgDarkGray && c <= color.FgLightWhite:
+			return tcell.PaletteColor(int(c-color.FgDarkGray) + 8)
+		case c >= color.BgBlack && c <= color.BgWhite:
+			return tcell.PaletteColor(int(c - color.BgBlack))
+		case c >= color.BgDarkGray && c <= color.BgLightWhite:
+			return tcell.PaletteColor(int(c-color.BgDarkGray) + 8)
+		}
+	case color.Color256:
+		return tcell.PaletteColor(int(c.Value()))
+	case color.RGBColor:
+		rgb := c.Values()
+		return tcell.NewRGBColor(int32(rgb[0]), int32(rgb[1]), int32(rgb[2]))
+	case color.Style:
+		for _, fg := range c {
+			if fg.IsFg() {
+				return tcellColor(fg)
+			}
+		}
+	}
+	return tcell.ColorDefault
+}
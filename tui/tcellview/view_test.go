@@ -0,0 +1,38 @@
+package tcellview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestColorJSONView(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(40, 10)
+
+	view, err := New(map[string]interface{}{"items": []interface{}{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view.HandleKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	view.HandleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	view.Draw(screen, 0, 0, 40, 10)
+	screen.Show()
+
+	cells, width, _ := screen.GetContents()
+	var row strings.Builder
+	for _, c := range cells[width : 2*width] {
+		row.WriteString(string(c.Runes))
+	}
+	if got := strings.TrimSpace(row.String()); got != `▸   "items": […] 2` {
+		t.Errorf("row = %q", got)
+	}
+	if got, want := view.Path(), "$.items"; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package colorjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// RoundTripError is returned in Verify mode when the output, with colors
+// removed, isn't valid JSON or doesn't decode to the encoded value.
+type RoundTripError struct {
+	// Output is the output without escape sequences.
+	Output string
+	Err    error
+}
+
+func (e *RoundTripError) Error() string {
+	return "colorjson: output does not round-trip: " + e.Err.Error()
+}
+
+func (e *RoundTripError) Unwrap() error {
+	return e.Err
+}
+
+// encodeVerified renders root, checks the output round-trips and only then
+// writes it out.
+func (f *Formatter) encodeVerified(root node) error {
+	var buf bytes.Buffer
+	inner := f.clone(&buf)
+	inner.Verify = false
+	inner.lines = nil
+	err := inner.encode(root)
+	f.stats = inner.stats
+	if err != nil {
+		return err
+	}
+
+	if err := f.verifyRoundTrip(StripANSI(buf.String()), root); err != nil {
+		return err
+	}
+
+	if _, err := f.Buffer.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return f.Flush()
+}
+
+// verifyRoundTrip checks that output is valid JSON and, if root can be
+// marshaled by encoding/json, that both decode to the same value. When f
+// leaves out or rewrites parts of root, output is compared with root
+// written the same way but without any presentation instead.
+func (f *Formatter) verifyRoundTrip(output string, root node) error {
+	got, err := decodeUseNumber([]byte(output))
+	if err != nil {
+		return &RoundTripError{Output: output, Err: err}
+	}
+
+	var data []byte
+	if f.reshapes() {
+		if data, err = f.expected(root); err != nil {
+			return nil
+		}
+	} else {
+		v := root.iface()
+		if v == nil && root.reflect().IsValid() {
+			// The value can't be obtained without panicking, so there is
+			// nothing to compare with.
+			return nil
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil
+		}
+	}
+	want, err := decodeUseNumber(data)
+	if err != nil {
+		return nil
+	}
+
+	if path, ok := sameJSON(want, got, "$"); !ok {
+		return &RoundTripError{Output: output, Err: fmt.Errorf("value differs at %s", path)}
+	}
+	return nil
+}
+
+// reshapes reports whether f leaves out, renames or rewrites parts of what
+// it encodes, so its output differs from what encoding/json writes.
+func (f *Formatter) reshapes() bool {
+	return len(f.IncludeKeys) > 0 || len(f.ExcludeKeys) > 0 || len(f.FilterRules) > 0 || f.FilterFunc != nil ||
+		f.KeyTransform != nil || f.IncludeUnexported || f.UseStringer || f.ExpandErrors
+}
+
+// expected writes root with f's filters and transformations but none of its
+// colors, layout or annotations.
+func (f *Formatter) expected(root node) ([]byte, error) {
+	var buf bytes.Buffer
+	g := NewFormatter(&buf)
+	g.DisabledColor = true
+	g.HighlightKeys = nil
+	g.IncludeKeys, g.ExcludeKeys, g.FilterRules, g.FilterFunc = f.IncludeKeys, f.ExcludeKeys, f.FilterRules, f.FilterFunc
+	g.KeyTransform, g.IncludeUnexported, g.UseStringer, g.ExpandErrors = f.KeyTransform, f.IncludeUnexported, f.UseStringer, f.ExpandErrors
+	g.FieldOrder, g.StdlibCompat = f.FieldOrder, f.StdlibCompat
+	if err := g.encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeUseNumber(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("data after the top-level value")
+	}
+	return v, nil
+}
+
+// sameJSON compares two decoded documents, treating numbers as equal if
+// they have the same value, and returns the path of the first difference.
+func sameJSON(want, got interface{}, path string) (string, bool) {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok || len(got) != len(want) {
+			return path, false
+		}
+		for k, w := range want {
+			g, ok := got[k]
+			if !ok {
+				return path + "." + k, false
+			}
+			if p, ok := sameJSON(w, g, path+"."+k); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok || len(got) != len(want) {
+			return path, false
+		}
+		for i := range want {
+			if p, ok := sameJSON(want[i], got[i], path+"["+strconv.Itoa(i)+"]"); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case json.Number:
+		got, ok := got.(json.Number)
+		if !ok {
+			return path, false
+		}
+		if want == got {
+			return "", true
+		}
+		w, werr := strconv.ParseFloat(string(want), 64)
+		g, gerr := strconv.ParseFloat(string(got), 64)
+		return path, werr == nil && gerr == nil && w == g
+	}
+	return path, reflect.DeepEqual(want, got)
+}